@@ -2,36 +2,50 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
-	"regexp"
 	"runtime"
 	"time"
 
-	"k8s.io/apimachinery/pkg/labels"
-
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"github.com/jaconi-io/secret-file-provider/pkg/apis/v1alpha1"
+	"github.com/jaconi-io/secret-file-provider/pkg/controllers/secrets"
 	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/filemapping"
+	"github.com/jaconi-io/secret-file-provider/pkg/finalizer"
+	"github.com/jaconi-io/secret-file-provider/pkg/inject"
+	"github.com/jaconi-io/secret-file-provider/pkg/selector"
 	"github.com/jaconi-io/secret-file-provider/pkg/setup"
+	"github.com/jaconi-io/secret-file-provider/pkg/templates"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	corev1 "k8s.io/api/core/v1"
-	"sigs.k8s.io/controller-runtime/pkg/cache"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
+func init() {
+	utilruntime.Must(v1alpha1.AddToScheme(scheme.Scheme))
+}
+
 func main() {
 	rootCmd := &cobra.Command{
 		Use:   "secret-file-provider",
@@ -46,62 +60,112 @@ func main() {
 				return fmt.Errorf("failed to get config for apiserver: %w", err)
 			}
 
-			var mgr manager.Manager
-			// connecting to the k8s api server fails if an e.g. istio sidecar has not yet finished starting up
-			retry(30, func() error {
-				ns := env.GetSingleNamespace()
-				if ns != "" {
-					// if only one NS is defined, we attach that to manager, so that
-					// we are able to use K8s roles instead of clusterroles
-					mgr, err = manager.New(cfg, manager.Options{
-						Metrics: server.Options{
-							BindAddress: ":" + viper.GetString(env.PortMetrics),
-						},
-						HealthProbeBindAddress: ":" + viper.GetString(env.PortHealthcheck),
-						Cache: cache.Options{
-							DefaultNamespaces: map[string]cache.Config{
-								ns: {},
-							},
-						},
-					})
-				} else {
-					mgr, err = manager.New(cfg, manager.Options{
-						Metrics: server.Options{
-							BindAddress: ":" + viper.GetString(env.PortMetrics),
-						},
-						HealthProbeBindAddress: ":" + viper.GetString(env.PortHealthcheck),
-					})
-				}
+			// the pod, mapping and secrets are read uncached, while the manager is not running
+			c, err := client.New(cfg, client.Options{})
+			if err != nil {
+				return fmt.Errorf("failed to create client: %w", err)
+			}
+
+			if err := configureFromPod(cmd.Context(), c); err != nil {
 				return err
-			})
+			}
+
+			var generation int64
+			if generation, err = applyMapping(cmd.Context(), c, 0); err != nil {
+				return fmt.Errorf("failed to apply mapping: %w", err)
+			}
+			env.LogConfiguration()
+
+			if err := validateConfiguration(); err != nil {
+				return err
+			}
+
+			if viper.GetBool(env.Oneshot) {
+				return setup.RunOnce(cmd.Context(), c)
+			}
 
 			go func() {
 				// handler is registered by blank import of net/http/pprof
 				slog.Info("", "error", http.ListenAndServe("localhost:"+viper.GetString(env.PortDebug), nil))
 			}()
 
-			// Add default liveness and readiness probes.
-			_ = mgr.AddHealthzCheck("ping", healthz.Ping)
-			_ = mgr.AddReadyzCheck("ping", healthz.Ping)
+			ctx := signals.SetupSignalHandler()
+			for {
+				// the manager is restarted, once the mapping changed
+				mgrCtx, restart := context.WithCancel(ctx)
+				mgr, err := newManager(cfg, generation, restart)
+				if err != nil {
+					restart()
+					return err
+				}
+
+				slog.Info("starting the service")
+
+				// Start the Service
+				err = mgr.Start(mgrCtx)
+				restart()
+				if err != nil {
+					return fmt.Errorf("manager exited with non-zero exit code: %w", err)
+				}
 
-			// register controller implementations
-			setup.RegisterControllers(mgr)
+				if ctx.Err() != nil {
+					slog.Info("retrieved SIGTERM")
+					cleanup(mgr)
+					slog.Info("cleanup completed")
+					return nil
+				}
 
-			slog.Info("starting the service")
+				// Nothing is running now, so the configuration can be changed safely. The content is removed with the
+				// previous configuration and written again with the new one, before the manager restarts.
+				if err := setup.Reconfigure(ctx, c, func() {
+					if generation, err = applyMapping(ctx, c, generation); err != nil {
+						slog.Error("failed to apply mapping, keeping the previous configuration", "error", err)
+					}
+					env.LogConfiguration()
+				}); err != nil {
+					slog.Error("failed to reconfigure content", "error", err)
+				}
+			}
+		},
+	}
+	sweepCmd := &cobra.Command{
+		Use:   "sweep-finalizers",
+		Short: "Remove orphaned finalizers",
+		Long:  "Remove finalizers of secret file provider sidecars, whose pods no longer exist, from all selected secrets.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.GetConfig()
+			if err != nil {
+				return fmt.Errorf("failed to get config for apiserver: %w", err)
+			}
 
-			// Start the Service
-			if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
-				return fmt.Errorf("manager exited with non-zero exit code: %w", err)
+			c, err := client.New(cfg, client.Options{})
+			if err != nil {
+				return fmt.Errorf("failed to create client: %w", err)
 			}
 
-			slog.Info("retrieved SIGTERM")
-			cleanup(mgr)
-			slog.Info("cleanup completed")
+			return finalizer.Sweep(cmd.Context(), c, c)
+		},
+	}
+
+	webhookCmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Serve the sidecar injection webhook",
+		Long:  "Mutating admission webhook injecting the secret file provider sidecar into annotated pods.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server := webhook.NewServer(webhook.Options{
+				Port:    viper.GetInt(env.WebhookPort),
+				CertDir: viper.GetString(env.WebhookCertDir),
+			})
+			server.Register("/mutate-pods", &admission.Webhook{Handler: inject.NewInjector(viper.GetString(env.WebhookImage))})
 
-			return nil
+			slog.Info("starting the webhook", "port", viper.GetInt(env.WebhookPort))
+			return server.Start(signals.SetupSignalHandler())
 		},
 	}
+
 	env.Bootstrap(rootCmd)
+	env.BootstrapWebhook(webhookCmd)
+	rootCmd.AddCommand(sweepCmd, webhookCmd)
 	rootCmd.Execute()
 }
 
@@ -121,57 +185,166 @@ func retry(maxAttempts int, action func() error) {
 	}
 }
 
-func cleanup(mgr manager.Manager) {
-	ctx := context.Background()
-	listOptions := &client.ListOptions{}
-
-	if viper.GetString(env.SecretLabelSelector) != "" {
-		labelSelector, err := labels.Parse(viper.GetString(env.SecretLabelSelector))
-		if err != nil {
-			slog.Error("cleanup failed due to invalid secret label selector", "error", err)
-			return
+// newManager creates a manager with all controllers for the current configuration. If a mapping is configured, restart
+// is called, once it differs from the given (applied) generation.
+func newManager(cfg *rest.Config, generation int64, restart func()) (manager.Manager, error) {
+	// secrets are filtered by the API server, and if namespaces are selected, we attach them to the manager, so
+	// that we are able to use K8s roles instead of clusterroles
+	cacheOptions, err := setup.CacheOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	var mgr manager.Manager
+	// connecting to the k8s api server fails if an e.g. istio sidecar has not yet finished starting up
+	retry(30, func() error {
+		mgr, err = manager.New(cfg, manager.Options{
+			Metrics: server.Options{
+				BindAddress: ":" + viper.GetString(env.PortMetrics),
+			},
+			HealthProbeBindAddress: ":" + viper.GetString(env.PortHealthcheck),
+			Cache:                  cacheOptions,
+			Client:                 setup.ClientOptions(),
+			// controllers are registered again, whenever the mapping changes
+			Controller: ctrlconfig.Controller{SkipNameValidation: ptr.To(filemapping.Configured())},
+		})
+		return err
+	})
+
+	// Add default liveness and readiness probes.
+	_ = mgr.AddHealthzCheck("ping", healthz.Ping)
+	_ = mgr.AddReadyzCheck("ping", healthz.Ping)
+
+	// register controller implementations
+	results := &filemapping.Results{}
+	if err := setup.RegisterControllers(mgr, results); err != nil {
+		return nil, fmt.Errorf("failed to register controllers: %w", err)
+	}
+
+	if filemapping.Configured() {
+		if err := setup.RegisterMappingController(mgr, generation, restart, results); err != nil {
+			return nil, fmt.Errorf("failed to register mapping controller: %w", err)
 		}
+	}
 
-		listOptions.LabelSelector = labelSelector
+	if dir := viper.GetString(env.TemplateDir); dir != "" {
+		_ = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			return templates.WatchPartials(ctx, dir)
+		}))
 	}
 
-	ns := env.GetSingleNamespace()
-	if ns != "" {
-		listOptions.Namespace = ns
+	if interval := viper.GetDuration(env.FinalizerSweepInterval); interval > 0 {
+		// pods are read uncached, there is no need to keep all of them in memory
+		_ = mgr.Add(&finalizer.Sweeper{Client: mgr.GetClient(), Reader: mgr.GetAPIReader(), Interval: interval})
 	}
 
-	secrets := &corev1.SecretList{}
-	if err := mgr.GetClient().List(context.Background(), secrets, listOptions); err != nil {
-		slog.Error("cleanup failed", "error", err)
-		return
+	return mgr, nil
+}
+
+// applyMapping applies the configured mapping, if any. Returns the generation of the applied mapping, or the given
+// generation, if the mapping could not be read.
+func applyMapping(ctx context.Context, c client.Client, generation int64) (int64, error) {
+	if !filemapping.Configured() {
+		return generation, nil
+	}
+
+	applied, err := setup.ApplyMapping(ctx, c, validateConfiguration)
+	if applied == 0 {
+		applied = generation
+	}
+	return applied, err
+}
+
+// configureFromPod overrides the configuration by the annotations of the pod the sidecar runs in, if the namespace of
+// the pod is known.
+func configureFromPod(ctx context.Context, c client.Reader) error {
+	if viper.GetString(env.PodNamespace) == "" {
+		return nil
+	}
+
+	var pod *corev1.Pod
+	// connecting to the k8s api server fails if an e.g. istio sidecar has not yet finished starting up
+	retry(30, func() error {
+		var err error
+		pod, err = setup.OwnPod(ctx, c)
+		return err
+	})
+
+	slog.Info("applying configuration from pod annotations", "namespace", pod.Namespace, "name", pod.Name)
+	return env.ApplyAnnotations(pod.Annotations)
+}
+
+// validateConfiguration parses and validates all configured templates, mappings, key filters and key transformations,
+// so errors surface at startup instead of on reconciliation of each secret.
+func validateConfiguration() error {
+	if viper.GetString(env.SecretFileNamePattern) == "" {
+		return fmt.Errorf("%s is required", env.SecretFileNamePattern)
 	}
 
-	// Filter for name pattern, if configured.
-	var accept func(corev1.Secret) bool
-	nameSelector := viper.GetString(env.SecretNameSelector)
-	if nameSelector != "" {
-		regex := regexp.MustCompilePOSIX(nameSelector)
-		accept = func(secret corev1.Secret) bool {
-			return regex.MatchString(secret.Name)
+	if dir := viper.GetString(env.TemplateDir); dir != "" {
+		if err := templates.LoadPartials(dir); err != nil {
+			return fmt.Errorf("invalid %s: %w", env.TemplateDir, err)
 		}
-	} else {
-		accept = func(corev1.Secret) bool {
-			return true
+	}
+
+	for _, key := range []string{
+		env.SecretFileNamePattern,
+		env.SecretFilePropertyPattern,
+		env.SecretContentSelector,
+		env.SecretFileListIdentity,
+		env.CallbackBody,
+	} {
+		if err := templates.Compile(viper.GetString(key)); err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
 		}
 	}
 
-	// Remove the finalizer from each secret.
-	for _, secret := range secrets.Items {
-		if !accept(secret) {
-			continue
+	if err := templates.CompileCondition(viper.GetString(env.SecretCondition)); err != nil {
+		return fmt.Errorf("invalid %s: %w", env.SecretCondition, err)
+	}
+
+	if templateFile := viper.GetString(env.TemplateFile); templateFile != "" {
+		if err := templates.CompileFile(templateFile); err != nil {
+			return fmt.Errorf("invalid %s: %w", env.TemplateFile, err)
 		}
+	}
 
-		if _, err := controllerutil.CreateOrPatch(ctx, mgr.GetClient(), &secret, func() error {
-			controllerutil.RemoveFinalizer(&secret, env.GetFinalizer())
-			return nil
-		}); err != nil {
-			slog.Error("cleanup failed for secret", "error", err)
-			continue
+	// without a positive interval, secrets would be polled in a tight loop
+	if len(env.GetPollNames()) > 0 {
+		if interval := viper.GetDuration(env.SecretPollInterval); interval <= 0 {
+			return fmt.Errorf("invalid %s: must be positive, got %s", env.SecretPollInterval, interval)
+		}
+		if jitter := viper.GetFloat64(env.SecretPollJitter); jitter < 0 {
+			return fmt.Errorf("invalid %s: must not be negative, got %v", env.SecretPollJitter, jitter)
 		}
 	}
+
+	if s, err := selector.New(); err != nil {
+		return err
+	} else if s.IsEmpty() {
+		return errors.New("no secret selector set")
+	}
+
+	if err := secrets.ValidateMapping(); err != nil {
+		return fmt.Errorf("invalid mapping: %w", err)
+	}
+
+	if err := secrets.ValidateList(); err != nil {
+		return fmt.Errorf("invalid list: %w", err)
+	}
+
+	if err := secrets.ValidateKeyFilter(); err != nil {
+		return fmt.Errorf("invalid key filter: %w", err)
+	}
+
+	if err := secrets.ValidateKeyTransformation(); err != nil {
+		return fmt.Errorf("invalid key transformation: %w", err)
+	}
+	return nil
+}
+
+func cleanup(mgr manager.Manager) {
+	if err := finalizer.Remove(context.Background(), mgr.GetClient()); err != nil {
+		slog.Error("cleanup failed", "error", err)
+	}
 }
@@ -0,0 +1,20 @@
+// Package v1alpha1 contains the API of the secret file provider.
+// +kubebuilder:object:generate=true
+// +groupName=secret-file-provider.jaconi.io
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group version of all types of this package.
+	GroupVersion = schema.GroupVersion{Group: "secret-file-provider.jaconi.io", Version: "v1alpha1"}
+
+	// SchemeBuilder registers the types of this package.
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types of this package to a scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
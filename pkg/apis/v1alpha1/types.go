@@ -0,0 +1,101 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Format of the target files.
+// +kubebuilder:validation:Enum=yaml;files
+type Format string
+
+const (
+	// FormatYAML writes the content of the secrets into YAML files.
+	FormatYAML Format = "yaml"
+	// FormatFiles writes each key of each secret into its own file.
+	FormatFiles Format = "files"
+)
+
+// SecretSelector selects the secrets to copy. Secrets have to match all given selectors.
+type SecretSelector struct {
+	// Label selector for secrets.
+	Label string `json:"label,omitempty"`
+	// Selector for the annotations of secrets, in label selector syntax.
+	Annotation string `json:"annotation,omitempty"`
+	// Name pattern for secrets, in regex format.
+	Name string `json:"name,omitempty"`
+	// Types of secrets, e.g. 'kubernetes.io/tls'.
+	Types []string `json:"types,omitempty"`
+	// Condition template, which has to render to a truthy value for each secret.
+	Condition string `json:"condition,omitempty"`
+}
+
+// FileSpec configures the target files.
+type FileSpec struct {
+	// NamePattern is the template for the target file name, or the target directory with format 'files'.
+	NamePattern string `json:"namePattern,omitempty"`
+	// PropertyPattern is the template for the base property path the content of each secret is mapped under.
+	PropertyPattern string `json:"propertyPattern,omitempty"`
+}
+
+// Callback is the HTTP call made for every file update.
+type Callback struct {
+	// URL to call for file updates.
+	URL string `json:"url,omitempty"`
+	// Method of the HTTP call.
+	// +kubebuilder:validation:Enum=GET;POST;HEAD;PUT;PATCH;DELETE
+	Method string `json:"method,omitempty"`
+	// Body template of the HTTP call.
+	Body string `json:"body,omitempty"`
+	// ContentType of the body.
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// SecretFileMappingSpec is the configuration of the sidecars reading the mapping. Fields, which are not set, default to
+// the environment variables and flags of the sidecars.
+type SecretFileMappingSpec struct {
+	// Selector for the secrets to copy.
+	Selector SecretSelector `json:"selector,omitempty"`
+	// Content template selecting specific fields of each secret.
+	Content string `json:"content,omitempty"`
+	// File configures the target files.
+	File FileSpec `json:"file,omitempty"`
+	// Format of the target files.
+	Format Format `json:"format,omitempty"`
+	// Callback made for every file update.
+	Callback *Callback `json:"callback,omitempty"`
+}
+
+// SecretFileMappingStatus is the state of the mapping, as observed by the sidecars reading it.
+type SecretFileMappingStatus struct {
+	// ObservedGeneration is the generation of the mapping last applied.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// SyncedSecrets is the number of secrets copied with the mapping.
+	SyncedSecrets int32 `json:"syncedSecrets"`
+	// FailedSecrets is the number of secrets, which could not be copied with the mapping, e.g. due to invalid content.
+	FailedSecrets int32 `json:"failedSecrets"`
+	// LastError is the reason the observed generation could not be applied, if any.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// SecretFileMapping configures, which secrets are copied into which files.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+type SecretFileMapping struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecretFileMappingSpec   `json:"spec,omitempty"`
+	Status SecretFileMappingStatus `json:"status,omitempty"`
+}
+
+// SecretFileMappingList contains a list of SecretFileMapping.
+// +kubebuilder:object:root=true
+type SecretFileMappingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SecretFileMapping `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SecretFileMapping{}, &SecretFileMappingList{})
+}
@@ -0,0 +1,132 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/file"
+	"github.com/jaconi-io/secret-file-provider/pkg/filemapping"
+	"github.com/jaconi-io/secret-file-provider/pkg/namespaces"
+	"github.com/jaconi-io/secret-file-provider/pkg/selector"
+	"github.com/jaconi-io/secret-file-provider/pkg/templates"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// FileReconciler renders a single template file (see [env.TemplateFile]) with all matching secrets. The file is
+// re-rendered on every change to any of the secrets, including deletion.
+type FileReconciler struct {
+	client.Client
+
+	// Recorder publishes events for secrets, which could not be processed. Optional.
+	Recorder events.EventRecorder
+
+	// Namespaces selects the namespaces secrets are considered in. Optional, if nil, all namespaces are considered.
+	Namespaces *namespaces.Selector
+
+	// Results records whether the secrets have been synced. Optional.
+	Results *filemapping.Results
+}
+
+var _ reconcile.Reconciler = &FileReconciler{}
+var _ manager.Runnable = &FileReconciler{}
+
+func (r *FileReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	// The changed secret is only required for reporting, as the file is always rendered with all matching secrets.
+	secret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, request.NamespacedName, secret); err != nil {
+		if !errors.IsNotFound(err) {
+			slog.Error("failed to read secret", "error", err)
+			return reconcile.Result{}, err
+		}
+		secret.Name = request.Name
+		secret.Namespace = request.Namespace
+	}
+
+	changed, err := r.render(ctx)
+	if err != nil {
+		return reconcile.Result{}, handleError(r.Recorder, secret, err)
+	}
+	if !changed {
+		return reconcile.Result{}, nil
+	}
+
+	return reconcile.Result{}, notify(secret)
+}
+
+// Start renders the template file once, so it exists even if no secret matches. Errors are logged only, as the file is
+// rendered again on every change of a matching secret.
+func (r *FileReconciler) Start(ctx context.Context) error {
+	if err := r.Render(ctx); err != nil {
+		slog.Error("failed to render template file", "error", err)
+	}
+	return nil
+}
+
+// Render the template file once with all matching secrets, without calling the callback.
+func (r *FileReconciler) Render(ctx context.Context) error {
+	_, err := r.render(ctx)
+	return err
+}
+
+// render the template file with all matching secrets. Returns true, if the file has been replaced.
+func (r *FileReconciler) render(ctx context.Context) (bool, error) {
+	secrets, err := selector.Secrets(ctx, r.Client)
+	if err != nil {
+		return false, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	// Secrets being deleted, in namespaces no longer selected, or not matching the condition, are no longer considered.
+	var active []corev1.Secret
+	for _, s := range secrets {
+		if s.DeletionTimestamp == nil && r.Namespaces.Matches(s.Namespace) && selector.Condition(&s) {
+			active = append(active, s)
+		}
+	}
+
+	changed, err := renderFile(active)
+	keys := make([]types.NamespacedName, 0, len(active))
+	for i := range active {
+		keys = append(keys, client.ObjectKeyFromObject(&active[i]))
+	}
+	r.Results.Replace(keys, err)
+	if err != nil {
+		return false, fmt.Errorf("failed to update content: %w", err)
+	}
+	return changed, nil
+}
+
+// renderFile renders the template file with the given secrets into the target file. The target file is only replaced,
+// if its content changed. Returns true, if the file has been replaced.
+func renderFile(secrets []corev1.Secret) (bool, error) {
+	content, err := templates.RenderFile(viper.GetString(env.TemplateFile), secrets)
+	if err != nil {
+		return false, err
+	}
+
+	filename, err := file.Confine(viper.GetString(env.SecretFileNamePattern))
+	if err != nil {
+		return false, err
+	}
+	existing, err := os.ReadFile(filename)
+	if err == nil && bytes.Equal(existing, content) {
+		return false, nil
+	}
+
+	if err := file.WriteAtomic(filename, content); err != nil {
+		return false, err
+	}
+
+	slog.Info("file written", "path", filename, "secrets", len(secrets))
+	return true, nil
+}
@@ -0,0 +1,103 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFileReconcile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	dir := t.TempDir()
+	templateFile := filepath.Join(dir, "template")
+	targetFile := filepath.Join(dir, "target", "clients.conf")
+	err := os.WriteFile(templateFile, []byte(`{{range .Secrets}}{{.Labels.company}}={{.Data.key1}}
+{{end}}`), 0644)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	viper.Set(env.TemplateFile, templateFile)
+	viper.Set(env.SecretFileNamePattern, targetFile)
+	viper.Set(env.SecretNameSelector, ".*")
+
+	secret1 := testSecret("acme")
+	secret2 := testSecret("company")
+	secret2.Name = "bar"
+	c := fake.NewClientBuilder().WithObjects(secret1, secret2).Build()
+	reconciler := &FileReconciler{Client: c}
+
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	content, err := os.ReadFile(targetFile)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(content)).To(Equal("company=value1\nacme=value1\n"))
+
+	// deleted secrets are dropped from the file
+	err = c.Delete(context.TODO(), secret1)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	content, err = os.ReadFile(targetFile)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(content)).To(Equal("company=value1\n"))
+}
+
+func TestFileReconcilerStart(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	dir := t.TempDir()
+	templateFile := filepath.Join(dir, "template")
+	targetFile := filepath.Join(dir, "clients.conf")
+	err := os.WriteFile(templateFile, []byte(`{{len .Secrets}} clients`), 0644)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	viper.Set(env.TemplateFile, templateFile)
+	viper.Set(env.SecretFileNamePattern, targetFile)
+	viper.Set(env.SecretNameSelector, ".*")
+
+	// the file is rendered on start, even though no secret matches
+	reconciler := &FileReconciler{Client: fake.NewClientBuilder().Build()}
+	g.Expect(reconciler.Start(context.TODO())).To(Succeed())
+
+	content, err := os.ReadFile(targetFile)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(content)).To(Equal("0 clients"))
+}
+
+func TestFileReconcileStrictTemplate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	dir := t.TempDir()
+	templateFile := filepath.Join(dir, "template")
+	targetFile := filepath.Join(dir, "clients.conf")
+	err := os.WriteFile(templateFile, []byte(`{{secret "default/missing" "key1"}}`), 0644)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	viper.Set(env.TemplateStrict, true)
+	viper.Set(env.TemplateFile, templateFile)
+	viper.Set(env.SecretFileNamePattern, targetFile)
+	viper.Set(env.SecretNameSelector, ".*")
+
+	recorder := events.NewFakeRecorder(1)
+	reconciler := &FileReconciler{Client: fake.NewClientBuilder().WithObjects(testSecret("acme")).Build(), Recorder: recorder}
+
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning TemplateFailed")))
+
+	_, err = os.Stat(targetFile)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
@@ -0,0 +1,47 @@
+package secrets
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/spf13/viper"
+)
+
+// ValidateKeyFilter checks the configured include, exclude, structured and typed patterns.
+func ValidateKeyFilter() error {
+	for _, key := range []string{env.SecretKeyInclude, env.SecretKeyExclude, env.SecretKeyStructured, env.SecretKeyTyped} {
+		for _, pattern := range patterns(key) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid %s pattern %q: %w", key, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// acceptKey returns true, if the given (K8s secret) key matches any include pattern (see [env.SecretKeyInclude]) and no
+// exclude pattern (see [env.SecretKeyExclude]). If no include patterns are configured, all keys are included. Patterns
+// are matched against the original key, before any transformation.
+func acceptKey(key string) bool {
+	include := patterns(env.SecretKeyInclude)
+	if len(include) > 0 && !matchAny(include, key) {
+		return false
+	}
+	return !matchAny(patterns(env.SecretKeyExclude), key)
+}
+
+// matchAny returns true, if the key matches any of the given glob patterns. Invalid patterns never match.
+func matchAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// patterns returns the comma separated glob patterns configured for the given key.
+func patterns(key string) []string {
+	return env.SplitList(viper.GetString(key))
+}
@@ -0,0 +1,40 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+)
+
+func TestAcceptKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	// everything is accepted by default
+	g.Expect(acceptKey("ca.crt")).To(BeTrue())
+	g.Expect(acceptKey("DB_URL")).To(BeTrue())
+
+	viper.Set(env.SecretKeyExclude, "ca.crt, *_PASSWORD")
+	g.Expect(acceptKey("ca.crt")).To(BeFalse())
+	g.Expect(acceptKey("ADMIN_PASSWORD")).To(BeFalse())
+	g.Expect(acceptKey("DB_URL")).To(BeTrue())
+
+	viper.Set(env.SecretKeyInclude, "*_URL,*_PASSWORD")
+	g.Expect(acceptKey("ca.crt")).To(BeFalse())
+	g.Expect(acceptKey("ADMIN_PASSWORD")).To(BeFalse())
+	g.Expect(acceptKey("DB_URL")).To(BeTrue())
+	g.Expect(acceptKey("DB_USER")).To(BeFalse())
+}
+
+func TestValidateKeyFilter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	g.Expect(ValidateKeyFilter()).To(Succeed())
+
+	viper.Set(env.SecretKeyInclude, "*_URL")
+	viper.Set(env.SecretKeyExclude, "[")
+	g.Expect(ValidateKeyFilter()).To(MatchError("invalid secret.key.exclude pattern \"[\": syntax error in pattern"))
+}
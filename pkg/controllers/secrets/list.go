@@ -0,0 +1,64 @@
+package secrets
+
+import (
+	"errors"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/maps"
+	"github.com/jaconi-io/secret-file-provider/pkg/templates"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ValidateList checks, that list output (see [env.SecretFileListIdentity]) is combined with a property pattern pointing
+// at the list and a single file.
+func ValidateList() error {
+	if viper.GetString(env.SecretFileListIdentity) == "" {
+		return nil
+	}
+
+	if viper.GetString(env.SecretFilePropertyPattern) == "" {
+		return errors.New("list identity is set, but no property pattern")
+	}
+
+	if viper.GetBool(env.SecretFileSingle) {
+		return errors.New("list identity and single files are set")
+	}
+
+	if viper.GetString(env.SecretFileListKey) == "" {
+		return errors.New("list identity is set, but no list key")
+	}
+
+	return nil
+}
+
+// listElement turns the content of the given secret into a single list element. The element is identified by the
+// rendered identity template (see [env.SecretFileListIdentity]), which is added to the element as property
+// [env.SecretFileListKey].
+//
+// Example:
+//
+//	secret.file.property.pattern = "clients"
+//	secret.file.list.identity    = "{{.Labels.company}}"
+//	secret.selector.mapping      = "CLIENT_ID=id;CLIENT_SECRET=secret"
+//
+// The resulting file will be:
+//
+//	clients:
+//	- name: acme
+//	  id: the-acme-id
+//	  secret: the-acme-secret
+//	- name: foobar
+//	  ...
+func listElement(secret *corev1.Secret, content map[interface{}]interface{}) (maps.Element, error) {
+	identity, err := templates.Render(viper.GetString(env.SecretFileListIdentity), secret)
+	if err != nil {
+		return maps.Element{}, err
+	}
+
+	key := viper.GetString(env.SecretFileListKey)
+	return maps.Element{
+		Key:   key,
+		Value: maps.Merge(content, map[interface{}]interface{}{key: identity}),
+	}, nil
+}
@@ -0,0 +1,120 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/maps"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReadSecretContent_list(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretFileListKey, env.DefaultSecretFileListKey)
+	viper.Set(env.SecretFileListIdentity, "{{.Labels.company}}")
+	viper.Set(env.SecretFilePropertyPattern, "spring.clients")
+	viper.Set(env.SecretContentMapping, "key1=id;key2=secret")
+
+	result, err := readSecretContent(testSecret("acme"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(map[interface{}]interface{}{
+		"spring": map[interface{}]interface{}{
+			"clients": maps.Element{
+				Key:   "name",
+				Value: map[interface{}]interface{}{"name": "acme", "id": "value1", "secret": "value2"},
+			},
+		},
+	}))
+
+	// single values are written as element with the key of the selector
+	viper.Set(env.SecretContentMapping, "")
+	viper.Set(env.SecretContentSelector, "{{.Data.key1}}")
+	viper.Set(env.SecretFileListKey, "company")
+	result, err = readSecretContent(testSecret("acme"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(map[interface{}]interface{}{
+		"spring": map[interface{}]interface{}{
+			"clients": maps.Element{
+				Key:   "company",
+				Value: map[interface{}]interface{}{"company": "acme", "key1": "value1"},
+			},
+		},
+	}))
+}
+
+func TestReconcileList(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	defer os.Remove(testfile)
+	viper.Set(env.SecretFileNamePattern, testfile)
+	viper.Set(env.SecretFileListKey, env.DefaultSecretFileListKey)
+	viper.Set(env.SecretFileListIdentity, "{{.Labels.company}}")
+	viper.Set(env.SecretFilePropertyPattern, "clients")
+	viper.Set(env.SecretContentMapping, "key1=id")
+	viper.Set(env.PodName, "pod1")
+	viper.Set(env.SecretDeletionWatch, "true")
+
+	// each secret contributes one element
+	for _, company := range []string{"acme", "company"} {
+		reconciler := &Reconciler{Client: fake.NewClientBuilder().WithObjects(testSecret(company)).Build()}
+		_, err := reconciler.Reconcile(context.TODO(), req)
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+	g.Expect(readTestFile()).To(Equal(map[interface{}]interface{}{
+		"clients": []interface{}{
+			map[interface{}]interface{}{"name": "acme", "id": "value1"},
+			map[interface{}]interface{}{"name": "company", "id": "value1"},
+		},
+	}))
+
+	// updates replace the element
+	secret := testSecret("acme")
+	secret.Data["key1"] = []byte("changed")
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build()}
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readTestFile()).To(Equal(map[interface{}]interface{}{
+		"clients": []interface{}{
+			map[interface{}]interface{}{"name": "acme", "id": "changed"},
+			map[interface{}]interface{}{"name": "company", "id": "value1"},
+		},
+	}))
+
+	// deletion removes the element
+	secret.ObjectMeta.Finalizers = []string{"jaconi.io/secret-file-provider-pod1"}
+	secret.ObjectMeta.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	reconciler = &Reconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build()}
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readTestFile()).To(Equal(map[interface{}]interface{}{
+		"clients": []interface{}{
+			map[interface{}]interface{}{"name": "company", "id": "value1"},
+		},
+	}))
+}
+
+func TestValidateList(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	g.Expect(ValidateList()).To(Succeed())
+
+	viper.Set(env.SecretFileListKey, env.DefaultSecretFileListKey)
+	viper.Set(env.SecretFileListIdentity, "{{.Name}}")
+	g.Expect(ValidateList()).To(MatchError("list identity is set, but no property pattern"))
+
+	viper.Set(env.SecretFilePropertyPattern, "clients")
+	g.Expect(ValidateList()).To(Succeed())
+
+	viper.Set(env.SecretFileSingle, true)
+	g.Expect(ValidateList()).To(MatchError("list identity and single files are set"))
+}
@@ -0,0 +1,110 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/logger"
+	"github.com/jaconi-io/secret-file-provider/pkg/maps"
+	"github.com/jaconi-io/secret-file-provider/pkg/templates"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// mapping places the value of a single (K8s secret) key at a templated property path.
+type mapping struct {
+	key          string
+	pathTemplate string
+}
+
+// ValidateMapping checks the configured key to property mapping.
+func ValidateMapping() error {
+	entries, err := mappings()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) > 0 && viper.GetString(env.SecretContentSelector) != "" {
+		return errors.New("content selector and mapping are set")
+	}
+
+	for _, m := range entries {
+		if err := templates.Compile(m.pathTemplate); err != nil {
+			return fmt.Errorf("invalid mapping for key %q: %w", m.key, err)
+		}
+	}
+	return nil
+}
+
+// mapContent places the value of each mapped key (see [env.SecretContentMapping]) at its rendered property path.
+// Keys missing in the secret are skipped. No key transformation is applied, as the property paths are explicit.
+//
+// Example:
+//
+//	secret.selector.mapping="CLIENT_ID=clients.{{.Labels.company}}.id;CLIENT_SECRET=clients.{{.Labels.company}}.secret"
+//
+// The resulting map will be:
+//
+//	clients: {
+//	  acme: {
+//	    id: the-acme-id,
+//	    secret: the-acme-secret
+//	  }
+//	}
+func mapContent(secret *corev1.Secret) (map[interface{}]interface{}, error) {
+	entries, err := mappings()
+	if err != nil {
+		return map[interface{}]interface{}{}, err
+	}
+
+	result := make(map[interface{}]interface{})
+	for _, m := range entries {
+		value, ok := secret.Data[m.key]
+		if !ok {
+			logger.New(secret).Debug("skipping mapping of missing key", "key", m.key)
+			continue
+		}
+
+		propertyPath, err := templates.Render(m.pathTemplate, secret)
+		if err != nil {
+			return map[interface{}]interface{}{}, err
+		}
+
+		var leaf interface{}
+		if structuredKey(m.key) {
+			leaf, err = parseDocument(m.key, value)
+		} else {
+			leaf, err = typedValue(secret, m.key)
+		}
+		if err != nil {
+			return map[interface{}]interface{}{}, err
+		}
+
+		nested, err := nest(propertyPath, leaf)
+		if err != nil {
+			return map[interface{}]interface{}{}, err
+		}
+		result = maps.Merge(result, nested)
+	}
+	return result, nil
+}
+
+// mappings parses the semicolon separated 'KEY=PROPERTY_PATH' entries of [env.SecretContentMapping].
+func mappings() ([]mapping, error) {
+	var result []mapping
+	for _, entry := range strings.Split(viper.GetString(env.SecretContentMapping), ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		key, pathTemplate, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(key) == "" || strings.TrimSpace(pathTemplate) == "" {
+			return nil, fmt.Errorf("invalid mapping %q; expecting KEY=PROPERTY_PATH", entry)
+		}
+
+		result = append(result, mapping{key: strings.TrimSpace(key), pathTemplate: strings.TrimSpace(pathTemplate)})
+	}
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReadSecretContent_mapping(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "auth-client-acme",
+			Labels: map[string]string{
+				"company": "acme",
+			},
+		},
+		Data: map[string][]byte{
+			"CLIENT_ID":     []byte("123-456"),
+			"CLIENT_SECRET": []byte("mySuperSecretSecret"),
+			"ADMIN":         []byte("root"),
+		},
+	}
+
+	viper.Set(env.SecretContentMapping, "CLIENT_ID=oauth.clients.{{.Labels.company}}.clientId; CLIENT_SECRET=oauth.clients.{{.Labels.company}}.clientSecret;MISSING=foo")
+	result, err := readSecretContent(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(map[interface{}]interface{}{
+		"oauth": map[interface{}]interface{}{
+			"clients": map[interface{}]interface{}{
+				"acme": map[interface{}]interface{}{
+					"clientId":     "123-456",
+					"clientSecret": "mySuperSecretSecret",
+				},
+			},
+		},
+	}))
+
+	// with property path prefix
+	viper.Set(env.SecretFilePropertyPattern, "spring")
+	viper.Set(env.SecretContentMapping, "CLIENT_ID=clientIds.{{.Labels.company}}")
+	result, err = readSecretContent(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(map[interface{}]interface{}{
+		"spring": map[interface{}]interface{}{
+			"clientIds": map[interface{}]interface{}{
+				"acme": "123-456",
+			},
+		},
+	}))
+}
+
+func TestValidateMapping(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	g.Expect(ValidateMapping()).To(Succeed())
+
+	viper.Set(env.SecretContentMapping, "CLIENT_ID=clients.{{.Labels.company}}.id;CLIENT_SECRET=clients.{{.Labels.company}}.secret")
+	g.Expect(ValidateMapping()).To(Succeed())
+
+	viper.Set(env.SecretContentMapping, "CLIENT_ID")
+	g.Expect(ValidateMapping()).To(MatchError("invalid mapping \"CLIENT_ID\"; expecting KEY=PROPERTY_PATH"))
+
+	viper.Set(env.SecretContentMapping, "CLIENT_ID=clients.{{.Lables.company}}.id")
+	g.Expect(ValidateMapping()).To(MatchError(ContainSubstring("invalid mapping for key \"CLIENT_ID\": validating template")))
+
+	viper.Set(env.SecretContentMapping, "CLIENT_ID=clients.id")
+	viper.Set(env.SecretContentSelector, "{{.Data.CLIENT_ID}}")
+	g.Expect(ValidateMapping()).To(MatchError("content selector and mapping are set"))
+}
@@ -1,10 +1,14 @@
 package secrets
 
 import (
+	"fmt"
 	"log/slog"
+	gomaps "maps"
+	"slices"
 	"strings"
 
 	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/maps"
 	"github.com/jaconi-io/secret-file-provider/pkg/templates"
 	"github.com/spf13/viper"
 	corev1 "k8s.io/api/core/v1"
@@ -19,7 +23,7 @@ import (
 // Example:
 //
 //	secret.selector.content     = "{{.Data.CLIENT_ID}}"
-//	secret.file.property.pattern="foo.bar.clientIds.{{.ObjectMeta.Labels.company}}"
+//	secret.file.property.pattern="foo.bar.clientIds.{{.Labels.company}}"
 //
 // The resulting map will be:
 //
@@ -35,6 +39,14 @@ import (
 func readSecretContent(secret *corev1.Secret) (map[interface{}]interface{}, error) {
 	propertyPattern := viper.GetString(env.SecretFilePropertyPattern)
 
+	if viper.GetString(env.SecretContentMapping) != "" {
+		mapContent, err := mapContent(secret)
+		if err != nil || len(propertyPattern) < 1 {
+			return mapContent, err
+		}
+		return nestAdditionalProperties(secret, mapContent, "")
+	}
+
 	mapContent, stringContent, err := extractContent(secret)
 	if err != nil {
 		return map[interface{}]interface{}{}, err
@@ -47,7 +59,7 @@ func readSecretContent(secret *corev1.Secret) (map[interface{}]interface{}, erro
 			// can return the already read in map
 			return mapContent, nil
 		}
-		return processSingleElement(stringContent), nil
+		return processSingleElement(secret, stringContent)
 	}
 
 	return nestAdditionalProperties(secret, mapContent, stringContent)
@@ -61,16 +73,46 @@ func extractContent(secret *corev1.Secret) (map[interface{}]interface{}, string,
 	stringContent := ""
 
 	// fill content with secret data and selectorTemplate
-	if len(selectorTemplate) < 1 {
-		// put all into map
-		for k, v := range secret.Data {
-			mapContent[transform(k)] = string(v)
+	if len(selectorTemplate) < 1 || !strings.Contains(selectorTemplate, "{{") {
+		if len(selectorTemplate) > 0 {
+			// not a go template, log warning and put all into map
+			slog.Warn("illegal selector pattern; expecting go template", "pattern", selectorTemplate)
 		}
-	} else if !strings.Contains(selectorTemplate, "{{") {
-		// not a go template, log warning and put all into map
-		slog.Warn("illegal selector pattern; expecting go template", "pattern", selectorTemplate)
-		for k, v := range secret.Data {
-			mapContent[transform(k)] = string(v)
+
+		// put all accepted keys into map; sorted, so nested keys (e.g. 'a.b') reliably overwrite plain ones ('a')
+		renamed := make(map[string]string)
+		for _, k := range slices.Sorted(gomaps.Keys(secret.Data)) {
+			if !acceptKey(k) {
+				continue
+			}
+
+			// documents are merged as they are, without the key
+			if structuredKey(k) {
+				document, err := parseDocument(k, secret.Data[k])
+				if err != nil {
+					return mapContent, stringContent, err
+				}
+				mapContent = maps.Merge(mapContent, document)
+				continue
+			}
+
+			path, err := transformPath(k, secret)
+			if err != nil {
+				return mapContent, stringContent, err
+			}
+			// keys renamed to the same path would overwrite each other
+			if other, ok := renamed[strings.Join(path, "\x00")]; ok {
+				return mapContent, stringContent, &contentError{
+					source: fmt.Sprintf("key %q", k),
+					err:    fmt.Errorf("renamed to %q like key %q", strings.Join(path, "."), other),
+				}
+			}
+			renamed[strings.Join(path, "\x00")] = k
+			value, err := typedValue(secret, k)
+			if err != nil {
+				return mapContent, stringContent, err
+			}
+			mapContent = maps.Merge(mapContent, nestSegments(path, value))
 		}
 	} else {
 		// Render template to string; do not put into map, as this is intended to be a plain string
@@ -85,13 +127,13 @@ func extractContent(secret *corev1.Secret) (map[interface{}]interface{}, string,
 
 // processSingleElement creates a map containing only the given string value as value and the last
 // path segment of the content selector as key
-func processSingleElement(stringContent string) map[interface{}]interface{} {
+func processSingleElement(secret *corev1.Secret, stringContent string) (map[interface{}]interface{}, error) {
 	selectorTemplate := viper.GetString(env.SecretContentSelector)
 
 	if len(selectorTemplate) < 1 {
 		// illegal configuration, should never happen
 		slog.Warn("single value but no selector found")
-		return make(map[interface{}]interface{})
+		return make(map[interface{}]interface{}), nil
 	}
 
 	// use last path segment of selector as key for the new map
@@ -102,13 +144,18 @@ func processSingleElement(stringContent string) map[interface{}]interface{} {
 		// remove tailing braces
 		key = strings.Replace(key, "}", "", -1)
 		// make sure the key (refering to secret key) is transformed if necessary
-		key = transform(key)
+		var err error
+		key, err = transform(key, secret)
+		if err != nil {
+			return map[interface{}]interface{}{}, err
+		}
 	}
-	return map[interface{}]interface{}{key: stringContent}
+	return map[interface{}]interface{}{key: stringContent}, nil
 }
 
 // nestAdditionalProperties will attach either the given map- or string-content to a mandatory property pattern
-// prefix, gotten via [env.SecretFilePropertyPattern].
+// prefix, gotten via [env.SecretFilePropertyPattern]. If the content is written as list element (see
+// [env.SecretFileListIdentity]), the property pattern points at the list.
 func nestAdditionalProperties(secret *corev1.Secret, mapContent map[interface{}]interface{}, stringContent string) (map[interface{}]interface{}, error) {
 	propertyPattern := viper.GetString(env.SecretFilePropertyPattern)
 	propertyPath, err := templates.Render(propertyPattern, secret)
@@ -116,33 +163,42 @@ func nestAdditionalProperties(secret *corev1.Secret, mapContent map[interface{}]
 		return map[interface{}]interface{}{}, err
 	}
 
-	result := make(map[interface{}]interface{})
-	current := result
-
-	// for each part in the property path: create a nested child map
-	properties := strings.Split(propertyPath, ".")
-	for idx, prop := range properties {
-		if idx < len(properties)-1 {
-			// still need to nest maps...
-			childMap := make(map[interface{}]interface{})
-			current[prop] = childMap
-			current = childMap
-		} else {
-			// finally reached leaf
-			if stringContent != "" {
-				current[prop] = stringContent
-			} else {
-				current[prop] = mapContent
+	if viper.GetString(env.SecretFileListIdentity) != "" {
+		if stringContent != "" {
+			mapContent, err = processSingleElement(secret, stringContent)
+			if err != nil {
+				return map[interface{}]interface{}{}, err
 			}
 		}
+
+		element, err := listElement(secret, mapContent)
+		if err != nil {
+			return map[interface{}]interface{}{}, err
+		}
+		return nest(propertyPath, element)
+	}
+
+	if stringContent != "" {
+		return nest(propertyPath, stringContent)
+	}
+	return nest(propertyPath, mapContent)
+}
+
+// nest creates nested maps and lists for each segment of the given property path, with the given value as leaf. See
+// [maps.ParsePath] for the path syntax.
+func nest(propertyPath string, value interface{}) (map[interface{}]interface{}, error) {
+	segments, err := maps.ParsePath(propertyPath)
+	if err != nil {
+		return map[interface{}]interface{}{}, err
 	}
-	return result, nil
+	return maps.Nest(segments, value), nil
 }
 
-func transform(key string) string {
-	transform := viper.GetString(env.SecretKeyTransformation)
-	if function, ok := keyTransformFunctions[transform]; ok {
-		return function(key)
+// nestSegments creates nested maps for each of the given property path segments, with the given value as leaf.
+func nestSegments(properties []string, value interface{}) map[interface{}]interface{} {
+	segments := make([]maps.Segment, len(properties))
+	for i, prop := range properties {
+		segments[i] = maps.Segment{Key: prop}
 	}
-	return key
+	return maps.Nest(segments, value)
 }
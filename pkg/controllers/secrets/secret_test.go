@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/maps"
 	"github.com/onsi/gomega"
 	"github.com/spf13/viper"
 	corev1 "k8s.io/api/core/v1"
@@ -55,6 +56,13 @@ func TestReadSecretContent_wholeContent(t *testing.T) {
 	result, err = readSecretContent(secret)
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(result).To(gomega.Equal(map[interface{}]interface{}{"bar": map[interface{}]interface{}{"key1": "value1", "key2": "value2"}}))
+
+	// with excluded keys, filtered before transformation
+	viper.Set(env.SecretKeyExclude, "*2")
+	viper.Set(env.SecretKeyTransformation, "ToScreamingSnake")
+	result, err = readSecretContent(secret)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result).To(gomega.Equal(map[interface{}]interface{}{"bar": map[interface{}]interface{}{"KEY_1": "value1"}}))
 }
 
 func TestReadSecretContent_singleSelect(t *testing.T) {
@@ -97,4 +105,126 @@ func TestReadSecretContent_singleSelect(t *testing.T) {
 	result, err = readSecretContent(secret)
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(result).To(gomega.Equal(map[interface{}]interface{}{"bar": "value1"}))
+
+	// with renamed key
+	viper.Set(env.SecretFilePropertyPattern, "")
+	viper.Set(env.SecretKeyRenameRegex, "^key=secret")
+	result, err = readSecretContent(secret)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result).To(gomega.Equal(map[interface{}]interface{}{"Secret1": "value1"}))
+}
+
+func TestReadSecretContent_nestedKeys(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer viper.Reset()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "1-2-3-4",
+		},
+		Data: map[string][]byte{
+			"spring.datasource.password": []byte("secret"),
+			"spring.datasource.username": []byte("admin"),
+			"spring.profile":             []byte("prod"),
+		},
+	}
+
+	// flat keys by default
+	result, err := readSecretContent(secret)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result).To(gomega.HaveKeyWithValue("spring.datasource.password", "secret"))
+
+	// nested with separator
+	viper.Set(env.SecretKeySeparator, ".")
+	viper.Set(env.SecretFilePropertyPattern, "config")
+	result, err = readSecretContent(secret)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result).To(gomega.Equal(map[interface{}]interface{}{
+		"config": map[interface{}]interface{}{
+			"spring": map[interface{}]interface{}{
+				"datasource": map[interface{}]interface{}{
+					"password": "secret",
+					"username": "admin",
+				},
+				"profile": "prod",
+			},
+		},
+	}))
+
+	// transformation functions are applied to each segment
+	secret.Data = map[string][]byte{
+		"SPRING__DATA_SOURCE__PASSWORD": []byte("secret"),
+	}
+	viper.Set(env.SecretKeySeparator, "__")
+	viper.Set(env.SecretKeyTransformation, "ToLowerCamel")
+	viper.Set(env.SecretFilePropertyPattern, "")
+	result, err = readSecretContent(secret)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result).To(gomega.Equal(map[interface{}]interface{}{
+		"spring": map[interface{}]interface{}{
+			"dataSource": map[interface{}]interface{}{
+				"password": "secret",
+			},
+		},
+	}))
+}
+
+func TestReadSecretContent_renameCollision(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer viper.Reset()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "1-2-3-4",
+		},
+		Data: map[string][]byte{
+			"APP_CLIENT_ID": []byte("app"),
+			"CLIENT_ID":     []byte("plain"),
+		},
+	}
+
+	// keys renamed to the same name do not overwrite each other silently
+	viper.Set(env.SecretKeyRenameRegex, "^APP_=")
+	_, err := readSecretContent(secret)
+	g.Expect(err).To(gomega.MatchError(`invalid content of key "CLIENT_ID": renamed to "CLIENT_ID" like key "APP_CLIENT_ID"`))
+	g.Expect(isContentError(err)).To(gomega.BeTrue())
+}
+
+func TestReadSecretContent_propertyPath(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer viper.Reset()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "1-2-3-4",
+			Labels: map[string]string{
+				"domain": "acme.com",
+			},
+		},
+		Data: map[string][]byte{
+			"CLIENT_ID": []byte("the-acme-id"),
+		},
+	}
+
+	// quoted segments keep dots of label values
+	viper.Set(env.SecretContentSelector, "{{.Data.CLIENT_ID}}")
+	viper.Set(env.SecretFilePropertyPattern, `clients."{{.Labels.domain}}".id`)
+	result, err := readSecretContent(secret)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result).To(gomega.Equal(map[interface{}]interface{}{
+		"clients": map[interface{}]interface{}{
+			"acme.com": map[interface{}]interface{}{"id": "the-acme-id"},
+		},
+	}))
+
+	// list elements are appended
+	viper.Set(env.SecretFilePropertyPattern, "clientIds[]")
+	result, err = readSecretContent(secret)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(result).To(gomega.Equal(map[interface{}]interface{}{"clientIds": maps.Append{"the-acme-id"}}))
+
+	// invalid paths are reported
+	viper.Set(env.SecretFilePropertyPattern, `clients."{{.Labels.domain}}`)
+	_, err = readSecretContent(secret)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("missing closing quote")))
 }
@@ -5,15 +5,23 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"reflect"
+	"sync"
 
 	"github.com/jaconi-io/secret-file-provider/pkg/callback"
 	"github.com/jaconi-io/secret-file-provider/pkg/env"
 	"github.com/jaconi-io/secret-file-provider/pkg/file"
+	"github.com/jaconi-io/secret-file-provider/pkg/filemapping"
 	"github.com/jaconi-io/secret-file-provider/pkg/logger"
 	"github.com/jaconi-io/secret-file-provider/pkg/maps"
+	"github.com/jaconi-io/secret-file-provider/pkg/metrics"
+	"github.com/jaconi-io/secret-file-provider/pkg/namespaces"
+	"github.com/jaconi-io/secret-file-provider/pkg/selector"
+	"github.com/jaconi-io/secret-file-provider/pkg/templates"
 	"github.com/spf13/viper"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -21,6 +29,19 @@ import (
 
 type Reconciler struct {
 	client.Client
+
+	// Recorder publishes events for secrets, which could not be processed. Optional.
+	Recorder events.EventRecorder
+
+	// Namespaces selects the namespaces secrets are considered in. The content of secrets in namespaces, which are no
+	// longer selected, is removed. Optional, if nil, all namespaces are considered.
+	Namespaces *namespaces.Selector
+
+	// Results records whether each secret has been synced. Optional.
+	Results *filemapping.Results
+
+	// written records the content written for each secret (see [written]).
+	written sync.Map
 }
 
 var _ reconcile.Reconciler = &Reconciler{}
@@ -31,20 +52,25 @@ func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (
 	if err := r.Client.Get(ctx, request.NamespacedName, secret); err != nil {
 		if errors.IsNotFound(err) {
 			// do nothing
+			r.Results.Forget(request.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		slog.Error("failed to read secret", "error", err)
 		return reconcile.Result{}, err
 	}
 
-	if secret.DeletionTimestamp != nil {
-		if !viper.GetBool(env.SecretDeletionWatch) {
+	// Content of secrets being deleted, in namespaces no longer selected, or no longer matching the condition, is removed.
+	if secret.DeletionTimestamp != nil || !r.Namespaces.Matches(secret.Namespace) || !selector.Condition(secret) {
+		if secret.DeletionTimestamp != nil && !viper.GetBool(env.SecretDeletionWatch) {
 			// ignore deletion
 			return reconcile.Result{}, nil
 		}
-		err := change(secret, remove)
+		err := change(secret, r.remove)
 		if err != nil {
-			return reconcile.Result{}, err
+			// If the content of the secret cannot be processed, keeping the finalizer would block the deletion forever.
+			if err := handleError(r.Recorder, secret, err); err != nil {
+				return reconcile.Result{}, err
+			}
 		}
 
 		// Remove the finalizer, once the cleanup completed successfully.
@@ -55,6 +81,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (
 			return reconcile.Result{}, fmt.Errorf("removing finalizer failed: %w", err)
 		}
 
+		r.Results.Forget(request.NamespacedName)
 		return reconcile.Result{}, nil
 	}
 
@@ -68,7 +95,38 @@ func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (
 		}
 	}
 
-	return reconcile.Result{}, change(secret, add)
+	if err := change(secret, r.add); err != nil {
+		r.Results.Record(request.NamespacedName, err)
+		return reconcile.Result{}, handleError(r.Recorder, secret, err)
+	}
+
+	r.Results.Record(request.NamespacedName, nil)
+	return reconcile.Result{}, nil
+}
+
+// handleError reports errors caused by the content of the given secret via logs, metrics and events. Retrying does not
+// resolve such errors as long as the secret is unchanged, so they are not returned. All other errors are returned as
+// is.
+func handleError(recorder events.EventRecorder, secret *corev1.Secret, err error) error {
+	var reason string
+	switch {
+	case templates.IsExecutionError(err):
+		reason = "TemplateFailed"
+	case isContentError(err):
+		reason = "InvalidContent"
+	case file.IsConfinementError(err):
+		reason = "PathRejected"
+	default:
+		return err
+	}
+
+	logger.New(secret).Error("failed to process secret", "reason", reason, "error", err)
+	metrics.SecretErrors.WithLabelValues(secret.Namespace, secret.Name, reason).Inc()
+	if recorder != nil {
+		recorder.Eventf(secret, nil, corev1.EventTypeWarning, reason, "Process", "%s", err.Error())
+	}
+
+	return nil
 }
 
 // change will call the given change function on the secret and call a probably
@@ -79,6 +137,12 @@ func change(secret *corev1.Secret, changeFunc func(*corev1.Secret) error) error
 	if err != nil {
 		return fmt.Errorf("failed to update content: %w", err)
 	}
+	return notify(secret)
+}
+
+// notify calls a probably existing callback endpoint about a change caused by the given secret.
+// Returns an error if anything went wrong
+func notify(secret *corev1.Secret) error {
 	retry, err := callback.Call(secret)
 	if err != nil {
 		if retry {
@@ -91,17 +155,67 @@ func change(secret *corev1.Secret, changeFunc func(*corev1.Secret) error) error
 	return nil
 }
 
+// Remove the files or file content belonging to the given secret, without calling the callback. Used to clean up before
+// the configuration changes, as the content is added again with the new configuration.
+func Remove(secret *corev1.Secret) error {
+	if viper.GetString(env.TemplateFile) != "" {
+		// the template file is rendered again with the new configuration
+		return nil
+	}
+	return remove(secret)
+}
+
+// written is the content written into a file for a secret.
+type written struct {
+	file    string
+	content map[interface{}]interface{}
+}
+
+// add writes the content of the secret and records it, see [Reconciler.remove]. The content recorded before is
+// replaced, so e.g. appended list elements of a changed value do not pile up.
+func (r *Reconciler) add(secret *corev1.Secret) error {
+	var previous *written
+	if w, ok := r.written.Load(client.ObjectKeyFromObject(secret)); ok {
+		previous = w.(*written)
+	}
+	w, err := add(secret, previous)
+	if err != nil {
+		return err
+	}
+	r.written.Store(client.ObjectKeyFromObject(secret), w)
+	return nil
+}
+
+// remove drops the content recorded for the secret. The current content of the secret might differ from the written
+// one, e.g. if the key deciding the condition has been deleted. Without record, e.g. after a restart, the current
+// content is removed.
+func (r *Reconciler) remove(secret *corev1.Secret) error {
+	if w, ok := r.written.LoadAndDelete(client.ObjectKeyFromObject(secret)); ok {
+		return drop(w.(*written))
+	}
+	return remove(secret)
+}
+
 // remove will remove the files or file content, belonging to the given secret
 // Returns potential error
 func remove(secret *corev1.Secret) error {
 	logger.New(secret).Debug("Removing content for secret")
 
-	// 1. read existing file content
 	f, err := file.Name(secret)
 	if err != nil {
 		return err
 	}
-	existingContent, err := file.ReadAll(f)
+	content, err := readSecretContent(secret)
+	if err != nil {
+		return err
+	}
+	return drop(&written{file: f, content: content})
+}
+
+// drop removes the written content from its file.
+func drop(w *written) error {
+	// 1. read existing file content
+	existingContent, err := file.ReadAll(w.file)
 	if err != nil {
 		if os.IsNotExist(err) {
 			existingContent = map[interface{}]interface{}{}
@@ -110,47 +224,52 @@ func remove(secret *corev1.Secret) error {
 		}
 	}
 
-	// 2. read content from secret
-	newContent, err := readSecretContent(secret)
-	if err != nil {
-		return err
-	}
+	// 2. drop written entries from existing map
+	resultingMap := maps.Drop(existingContent, w.content)
 
-	// 3. drop new entries from existing map
-	resultingMap := maps.Drop(existingContent, newContent)
-
-	// 4. write to file
-	return file.WriteAll(f, resultingMap)
+	// 3. write to file
+	return file.WriteAll(w.file, resultingMap)
 }
 
-// add will create the files or file content, belonging to the given secret
-// Returns potential error
-func add(secret *corev1.Secret) error {
+// add will create the files or file content, belonging to the given secret, replacing the previously written content,
+// if any.
+// Returns the written content or a potential error
+func add(secret *corev1.Secret, previous *written) (*written, error) {
 	logger.New(secret).Debug("Adding content for secret")
 
 	// 1. read existing file content
 	f, err := file.Name(secret)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	existingContent, err := file.ReadAll(f)
 	if err != nil {
 		if os.IsNotExist(err) {
 			existingContent = map[interface{}]interface{}{}
 		} else {
-			return err
+			return nil, err
 		}
 	}
 
 	// 2. read content from secret
 	newContent, err := readSecretContent(secret)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// 3. merge maps
+	// 3. drop previous content, unless unchanged, and merge maps
+	if previous != nil && !reflect.DeepEqual(previous.content, newContent) {
+		if previous.file == f {
+			existingContent = maps.Drop(existingContent, previous.content)
+		} else if err := drop(previous); err != nil {
+			return nil, err
+		}
+	}
 	resultingMap := maps.Union(existingContent, newContent)
 
 	// 4. write to file
-	return file.WriteAll(f, resultingMap)
+	if err := file.WriteAll(f, resultingMap); err != nil {
+		return nil, err
+	}
+	return &written{file: f, content: newContent}, nil
 }
@@ -10,13 +10,18 @@ import (
 	"time"
 
 	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/filemapping"
+	"github.com/jaconi-io/secret-file-provider/pkg/metrics"
+	"github.com/jaconi-io/secret-file-provider/pkg/namespaces"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -151,6 +156,168 @@ func TestReconcileWithDeletion(t *testing.T) {
 	g.Expect(result["uni"]).To(Equal(map[interface{}]interface{}{"key1": "value1", "key2": "value2"}))
 }
 
+func TestReconcileListElements(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	defer os.Remove(testfile)
+	viper.Set(env.SecretContentSelector, "{{.Data.key1}}")
+	viper.Set(env.SecretFileNamePattern, testfile)
+	viper.Set(env.SecretFilePropertyPattern, "clients[]")
+	viper.Set(env.PodName, "pod1")
+	viper.Set(env.SecretDeletionWatch, "true")
+
+	// Append two elements
+	secret1 := testSecret("acme")
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithObjects(secret1).Build()}
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).To(BeNil())
+
+	viper.Set(env.SecretContentSelector, "{{.Data.key2}}")
+	secret2 := testSecret("company")
+	reconciler = &Reconciler{Client: fake.NewClientBuilder().WithObjects(secret2).Build()}
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).To(BeNil())
+
+	// reconciling again does not duplicate the element
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).To(BeNil())
+
+	result := readTestFile()
+	g.Expect(result["clients"]).To(Equal([]interface{}{"value1", "value2"}))
+
+	// the element appended for a deleted secret is removed
+	viper.Set(env.SecretContentSelector, "{{.Data.key1}}")
+	secret1.ObjectMeta.Finalizers = []string{"jaconi.io/secret-file-provider-pod1"}
+	secret1.ObjectMeta.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	reconciler = &Reconciler{Client: fake.NewClientBuilder().WithObjects(secret1).Build()}
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).To(BeNil())
+
+	result = readTestFile()
+	g.Expect(result["clients"]).To(Equal([]interface{}{"value2"}))
+}
+
+func TestReconcileListElementChanged(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	defer os.Remove(testfile)
+	viper.Set(env.SecretContentSelector, "{{.Data.key1}}")
+	viper.Set(env.SecretFileNamePattern, testfile)
+	viper.Set(env.SecretFilePropertyPattern, "clients[]")
+	viper.Set(env.PodName, "pod1")
+
+	secret := testSecret("acme")
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build()}
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).To(BeNil())
+
+	// the changed value replaces the appended element
+	secret.Data["key1"] = []byte("rotated")
+	g.Expect(reconciler.Client.Update(context.TODO(), secret)).To(Succeed())
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).To(BeNil())
+
+	g.Expect(readTestFile()["clients"]).To(Equal([]interface{}{"rotated"}))
+}
+
+func TestReconcileNamespaceSelector(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	defer os.Remove(testfile)
+	viper.Set(env.SecretContentSelector, "{{.Data.key1}}")
+	viper.Set(env.SecretFileNamePattern, testfile)
+	viper.Set(env.SecretFilePropertyPattern, "{{.ObjectMeta.Labels.company}}")
+	viper.Set(env.PodName, "pod1")
+	viper.Set(env.SecretDeletionWatch, "true")
+	viper.Set(env.SecretNamespaceLabelSelector, "secret-file-provider=enabled")
+
+	selector, err := namespaces.New()
+	g.Expect(err).NotTo(HaveOccurred())
+	ns := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+		Name:   req.Namespace,
+		Labels: map[string]string{"secret-file-provider": "enabled"},
+	}}
+	g.Expect(selector.Update(ns)).To(BeTrue())
+
+	secret := testSecret("acme")
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build(), Namespaces: selector}
+
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readTestFile()).To(HaveKeyWithValue("acme", "value1"))
+
+	// content and finalizer are removed, once the namespace stops matching
+	ns.Labels = nil
+	g.Expect(selector.Update(ns)).To(BeTrue())
+
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readTestFile()).NotTo(HaveKey("acme"))
+
+	err = reconciler.Client.Get(context.Background(), req.NamespacedName, secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secret.Finalizers).To(BeEmpty())
+}
+
+func TestReconcileCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	defer os.Remove(testfile)
+	viper.Set(env.SecretContentSelector, "{{.Data.key1}}")
+	viper.Set(env.SecretFileNamePattern, testfile)
+	viper.Set(env.SecretFilePropertyPattern, "{{.ObjectMeta.Labels.company}}")
+	viper.Set(env.PodName, "pod1")
+	viper.Set(env.SecretCondition, `{{ ne .ObjectMeta.Labels.disabled "true" }}`)
+
+	secret := testSecret("acme")
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build()}
+
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readTestFile()).To(HaveKeyWithValue("acme", "value1"))
+
+	// content is removed, once the secret stops matching the condition
+	secret.Labels["disabled"] = "true"
+	g.Expect(reconciler.Client.Update(context.TODO(), secret)).To(Succeed())
+
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readTestFile()).NotTo(HaveKey("acme"))
+}
+
+func TestReconcileConditionKeyRemoved(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	defer os.Remove(testfile)
+	viper.Set(env.SecretFileNamePattern, testfile)
+	viper.Set(env.SecretFilePropertyPattern, "{{.ObjectMeta.Labels.company}}")
+	viper.Set(env.PodName, "pod1")
+	viper.Set(env.SecretCondition, `{{ if index .Data "key1" }}true{{ end }}`)
+
+	secret := testSecret("acme")
+	results := &filemapping.Results{}
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build(), Results: results}
+
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readTestFile()).To(HaveKeyWithValue("acme", map[interface{}]interface{}{"key1": "value1", "key2": "value2"}))
+	g.Expect(results.Counts()).To(BeEquivalentTo(1))
+
+	// the content written is removed, even though the key deciding the condition no longer exists
+	delete(secret.Data, "key1")
+	g.Expect(reconciler.Client.Update(context.TODO(), secret)).To(Succeed())
+
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readTestFile()).NotTo(HaveKey("acme"))
+	g.Expect(results.Counts()).To(BeEquivalentTo(0))
+}
+
 func TestReconcileAddFinalizer(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -204,6 +371,83 @@ func TestReconcileRemoveFinalizer(t *testing.T) {
 	g.Expect(errors.IsNotFound(err)).To(BeTrue())
 }
 
+func TestReconcileStrictTemplate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	defer os.Remove(testfile)
+	viper.Set(env.TemplateStrict, true)
+	viper.Set(env.SecretContentSelector, "{{.Data.missing}}")
+	viper.Set(env.SecretFileNamePattern, testfile)
+	viper.Set(env.PodName, "pod1")
+
+	recorder := events.NewFakeRecorder(1)
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithObjects(testSecret("acme")).Build(), Recorder: recorder}
+
+	// the error is reported, but not returned, as retrying will not help
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning TemplateFailed")))
+	g.Expect(testutil.ToFloat64(metrics.SecretErrors.WithLabelValues(req.Namespace, req.Name, "TemplateFailed"))).To(BeNumerically(">", 0))
+
+	// no file has been written
+	_, err = os.Stat(testfile)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
+func TestReconcileFilePerKeyNested(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	dir := t.TempDir()
+	viper.Set(env.SecretFileSingle, true)
+	viper.Set(env.SecretFileNamePattern, dir)
+	viper.Set(env.SecretFilePropertyPattern, "{{.ObjectMeta.Labels.company}}")
+	viper.Set(env.PodName, "pod1")
+
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithObjects(testSecret("acme")).Build()}
+
+	// the property path becomes a directory, each key a file
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	b, err := os.ReadFile(filepath.Join(dir, "acme", "key1"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(Equal("value1"))
+
+	// the content of secrets no longer matching is removed again
+	viper.Set(env.SecretCondition, "false")
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	files, err := os.ReadDir(dir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(files).To(BeEmpty())
+}
+
+func TestReconcileOutsideRoot(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	root := t.TempDir()
+	viper.Set(env.SecretFileRoot, root)
+	viper.Set(env.SecretFileNamePattern, root+"/{{.ObjectMeta.Labels.company}}/secret.yaml")
+	viper.Set(env.PodName, "pod1")
+
+	recorder := events.NewFakeRecorder(1)
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithObjects(testSecret("../escaped")).Build(), Recorder: recorder}
+
+	// the path is rejected for this secret only, retrying will not help
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning PathRejected")))
+	g.Expect(testutil.ToFloat64(metrics.SecretErrors.WithLabelValues(req.Namespace, req.Name, "PathRejected"))).To(BeNumerically(">", 0))
+
+	// nothing has been written outside of the root
+	_, err = os.Stat(filepath.Join(filepath.Dir(root), "escaped"))
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
 func readTestFile() map[interface{}]interface{} {
 	bytes, err := os.ReadFile(testfile)
 	if err != nil {
@@ -0,0 +1,55 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"gopkg.in/yaml.v2"
+)
+
+// contentError is caused by invalid content of a secret, e.g. a structured value, which cannot be parsed. Retrying does
+// not help, until the secret is changed.
+type contentError struct {
+	// source of the content, e.g. 'key "application.yaml"'
+	source string
+	err    error
+}
+
+func (e *contentError) Error() string {
+	return fmt.Sprintf("invalid content of %s: %v", e.source, e.err)
+}
+
+func (e *contentError) Unwrap() error {
+	return e.err
+}
+
+// isContentError returns true, if the given error is caused by invalid content of a secret.
+func isContentError(err error) bool {
+	var contentErr *contentError
+	return errors.As(err, &contentErr)
+}
+
+// structuredKey returns true, if the value of the given (K8s secret) key is a YAML or JSON document, which is merged
+// into the file as tree instead of being copied as plain string (see [env.SecretKeyStructured]).
+func structuredKey(key string) bool {
+	return matchAny(patterns(env.SecretKeyStructured), key)
+}
+
+// parseDocument parses the YAML or JSON document stored in the given key. The document has to be a map, so it can be
+// merged into the file.
+func parseDocument(key string, value []byte) (map[interface{}]interface{}, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(value, &document); err != nil {
+		return nil, &contentError{source: fmt.Sprintf("key %q", key), err: err}
+	}
+
+	switch document := document.(type) {
+	case nil:
+		return map[interface{}]interface{}{}, nil
+	case map[interface{}]interface{}:
+		return document, nil
+	default:
+		return nil, &contentError{source: fmt.Sprintf("key %q", key), err: fmt.Errorf("expecting a map, got %T", document)}
+	}
+}
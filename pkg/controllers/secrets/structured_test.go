@@ -0,0 +1,153 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/metrics"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseDocument(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	document, err := parseDocument("application.yaml", []byte("server:\n  port: 8080\n"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(document).To(Equal(map[interface{}]interface{}{"server": map[interface{}]interface{}{"port": 8080}}))
+
+	document, err = parseDocument("config.json", []byte(`{"server": {"hosts": ["a", "b"]}}`))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(document).To(Equal(map[interface{}]interface{}{"server": map[interface{}]interface{}{"hosts": []interface{}{"a", "b"}}}))
+
+	document, err = parseDocument("empty.yaml", []byte(""))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(document).To(BeEmpty())
+
+	_, err = parseDocument("list.yaml", []byte("- a\n- b\n"))
+	g.Expect(err).To(MatchError(ContainSubstring("expecting a map")))
+	g.Expect(isContentError(err)).To(BeTrue())
+
+	_, err = parseDocument("invalid.yaml", []byte("server: [port"))
+	g.Expect(err).To(MatchError(ContainSubstring(`invalid content of key "invalid.yaml"`)))
+	g.Expect(isContentError(err)).To(BeTrue())
+}
+
+func TestReadSecretContent_structured(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "1-2-3-4",
+		},
+		Data: map[string][]byte{
+			"application.yaml": []byte("spring:\n  datasource:\n    username: admin\n"),
+			"PASSWORD":         []byte("secret"),
+		},
+	}
+
+	// documents are merged at the property path, other keys are copied as strings
+	viper.Set(env.SecretKeyStructured, "*.yaml")
+	viper.Set(env.SecretFilePropertyPattern, "config")
+	result, err := readSecretContent(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(map[interface{}]interface{}{
+		"config": map[interface{}]interface{}{
+			"spring": map[interface{}]interface{}{
+				"datasource": map[interface{}]interface{}{"username": "admin"},
+			},
+			"PASSWORD": "secret",
+		},
+	}))
+
+	// mapped documents are placed at the mapped path
+	viper.Set(env.SecretFilePropertyPattern, "")
+	viper.Set(env.SecretContentMapping, "application.yaml=app;PASSWORD=app.spring.datasource.password")
+	result, err = readSecretContent(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(map[interface{}]interface{}{
+		"app": map[interface{}]interface{}{
+			"spring": map[interface{}]interface{}{
+				"datasource": map[interface{}]interface{}{"username": "admin", "password": "secret"},
+			},
+		},
+	}))
+}
+
+func TestReconcileStructured(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	defer os.Remove(testfile)
+	viper.Set(env.SecretKeyStructured, "application.yaml")
+	viper.Set(env.SecretFileNamePattern, testfile)
+	viper.Set(env.PodName, "pod1")
+	viper.Set(env.SecretDeletionWatch, "true")
+
+	err := os.WriteFile(testfile, []byte("server:\n  port: 8080\n"), 0644)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      req.Name,
+			Namespace: req.Namespace,
+		},
+		Data: map[string][]byte{
+			"application.yaml": []byte("server:\n  ssl:\n    enabled: true\n  hosts: [a, b]\n"),
+		},
+	}
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build()}
+
+	// document is deep-merged into the existing file
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readTestFile()).To(Equal(map[interface{}]interface{}{
+		"server": map[interface{}]interface{}{
+			"port":  8080,
+			"ssl":   map[interface{}]interface{}{"enabled": true},
+			"hosts": []interface{}{"a", "b"},
+		},
+	}))
+
+	// deletion removes exactly the merged document
+	secret.ObjectMeta.Finalizers = []string{"jaconi.io/secret-file-provider-pod1"}
+	secret.ObjectMeta.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	reconciler = &Reconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build()}
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readTestFile()).To(Equal(map[interface{}]interface{}{
+		"server": map[interface{}]interface{}{"port": 8080},
+	}))
+}
+
+func TestReconcileInvalidContent(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	defer os.Remove(testfile)
+	viper.Set(env.SecretKeyStructured, "*")
+	viper.Set(env.SecretFileNamePattern, testfile)
+	viper.Set(env.PodName, "pod1")
+
+	recorder := events.NewFakeRecorder(1)
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithObjects(testSecret("acme")).Build(), Recorder: recorder}
+
+	// the error is reported, but not returned, as retrying will not help
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning InvalidContent")))
+	g.Expect(testutil.ToFloat64(metrics.SecretErrors.WithLabelValues(req.Namespace, req.Name, "InvalidContent"))).To(BeNumerically(">", 0))
+
+	// no file has been written
+	_, err = os.Stat(testfile)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
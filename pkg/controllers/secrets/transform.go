@@ -1,9 +1,16 @@
 package secrets
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/iancoleman/strcase"
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/templates"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
 )
 
 var keyTransformFunctions = map[string]func(string) string{
@@ -22,3 +29,171 @@ func toCamel(val string) string {
 func toLowerCamel(val string) string {
 	return strcase.ToLowerCamel(strings.ToLower(val))
 }
+
+// renameRule replaces all matches of a regular expression in a key.
+type renameRule struct {
+	regex       *regexp.Regexp
+	replacement string
+}
+
+// renames contains the parsed rename table and rules.
+type renames struct {
+	table map[string]string
+	rules []renameRule
+}
+
+// renameConfig identifies the configuration [renames] have been parsed from.
+type renameConfig struct {
+	table string
+	regex string
+}
+
+// parsedRenames caches [renames] by their [renameConfig], so the rules are only parsed and compiled once instead of for
+// every key of every secret.
+var parsedRenames sync.Map
+
+// ValidateKeyTransformation checks the configured rename table, rename rules, rename template and transformation
+// functions.
+func ValidateKeyTransformation() error {
+	if _, err := currentRenames(); err != nil {
+		return err
+	}
+
+	for _, name := range transformFunctionNames() {
+		if _, ok := keyTransformFunctions[name]; !ok {
+			return fmt.Errorf("unknown key transformation function %q", name)
+		}
+	}
+
+	return templates.CompileKey(viper.GetString(env.SecretKeyRenameTemplate))
+}
+
+// transform applies all configured transformations to the given (K8s secret) key in the following order:
+//
+//  1. the rename table, see [env.SecretKeyRenameTable]
+//  2. the regex rename rules, see [env.SecretKeyRenameRegex]
+//  3. the rename template, see [env.SecretKeyRenameTemplate]
+//  4. the transformation functions, see [env.SecretKeyTransformation]
+func transform(key string, secret *corev1.Secret) (string, error) {
+	key, err := rename(key, secret)
+	if err != nil {
+		return "", err
+	}
+	return convert(key), nil
+}
+
+// transformPath transforms the given (K8s secret) key like [transform], but splits the renamed key into segments at
+// the configured separator (see [env.SecretKeySeparator]). The transformation functions are applied to each segment,
+// so they do not remove the separators. Without separator, the result consists of a single segment.
+func transformPath(key string, secret *corev1.Secret) ([]string, error) {
+	key, err := rename(key, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := []string{key}
+	if separator := viper.GetString(env.SecretKeySeparator); separator != "" {
+		segments = strings.Split(key, separator)
+	}
+
+	for i, segment := range segments {
+		segments[i] = convert(segment)
+	}
+	return segments, nil
+}
+
+// rename applies the rename table, regex rules and template to the given key.
+func rename(key string, secret *corev1.Secret) (string, error) {
+	renames, err := currentRenames()
+	if err != nil {
+		return "", err
+	}
+
+	if renamed, ok := renames.table[key]; ok {
+		key = renamed
+	}
+	for _, rule := range renames.rules {
+		key = rule.regex.ReplaceAllString(key, rule.replacement)
+	}
+
+	if pattern := viper.GetString(env.SecretKeyRenameTemplate); pattern != "" {
+		return templates.RenderKey(pattern, key, secret)
+	}
+	return key, nil
+}
+
+// convert applies the transformation functions to the given key.
+func convert(key string) string {
+	for _, name := range transformFunctionNames() {
+		if function, ok := keyTransformFunctions[name]; ok {
+			key = function(key)
+		}
+	}
+	return key
+}
+
+// currentRenames returns the parsed rename table and rules of the current configuration.
+func currentRenames() (*renames, error) {
+	config := renameConfig{
+		table: viper.GetString(env.SecretKeyRenameTable),
+		regex: viper.GetString(env.SecretKeyRenameRegex),
+	}
+	if parsed, ok := parsedRenames.Load(config); ok {
+		return parsed.(*renames), nil
+	}
+
+	table, err := renameTable(config.table)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := renameRules(config.regex)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := &renames{table: table, rules: rules}
+	parsedRenames.Store(config, parsed)
+	return parsed, nil
+}
+
+// renameTable parses the comma separated 'OLD=NEW' pairs of [env.SecretKeyRenameTable].
+func renameTable(config string) (map[string]string, error) {
+	table := map[string]string{}
+	for _, pair := range env.SplitList(config) {
+		old, new, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid key rename table entry %q; expecting OLD=NEW", pair)
+		}
+		table[strings.TrimSpace(old)] = strings.TrimSpace(new)
+	}
+	return table, nil
+}
+
+// renameRules parses the semicolon separated 'REGEX=REPLACEMENT' rules of [env.SecretKeyRenameRegex]. Inside the
+// replacement, '$' signs are interpreted as in [regexp.Regexp.ReplaceAllString].
+func renameRules(config string) ([]renameRule, error) {
+	var rules []renameRule
+	for _, rule := range strings.Split(config, ";") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+
+		pattern, replacement, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid key rename rule %q; expecting REGEX=REPLACEMENT", rule)
+		}
+
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key rename rule %q: %w", rule, err)
+		}
+
+		rules = append(rules, renameRule{regex: regex, replacement: replacement})
+	}
+	return rules, nil
+}
+
+// transformFunctionNames returns the names of all configured transformation functions.
+func transformFunctionNames() []string {
+	return env.SplitList(viper.GetString(env.SecretKeyTransformation))
+}
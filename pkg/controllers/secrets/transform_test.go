@@ -3,7 +3,11 @@ package secrets
 import (
 	"testing"
 
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
 	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestToCamel(t *testing.T) {
@@ -23,3 +27,88 @@ func TestToLowerCamel(t *testing.T) {
 	g.Expect(toLowerCamel("UHH-ohh")).To(Equal("uhhOhh"))
 	g.Expect(toLowerCamel("uHH-ohh")).To(Equal("uhhOhh"))
 }
+
+func TestTransform(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{"prefix": "spring"},
+		},
+	}
+
+	// no transformation
+	g.Expect(transform("APP_CLIENT_ID", secret)).To(Equal("APP_CLIENT_ID"))
+
+	// single transformation function
+	viper.Set(env.SecretKeyTransformation, "ToLowerCamel")
+	g.Expect(transform("APP_CLIENT_ID", secret)).To(Equal("appClientId"))
+
+	// chained transformation functions
+	viper.Set(env.SecretKeyTransformation, "ToLowerCamel, ToKebab")
+	g.Expect(transform("APP_CLIENT_ID", secret)).To(Equal("app-client-id"))
+
+	// regex rename rules before transformation functions
+	viper.Set(env.SecretKeyRenameRegex, "^APP_=;_ID$=_IDENTIFIER")
+	g.Expect(transform("APP_CLIENT_ID", secret)).To(Equal("client-identifier"))
+
+	// rename table before regex rules
+	viper.Set(env.SecretKeyRenameTable, "APP_CLIENT_ID=APP_USER, FOO=BAR")
+	g.Expect(transform("APP_CLIENT_ID", secret)).To(Equal("user"))
+	g.Expect(transform("APP_CLIENT_SECRET", secret)).To(Equal("client-secret"))
+
+	// template with access to key and secret
+	viper.Set(env.SecretKeyRenameTemplate, "{{.Labels.prefix}}_{{.Key}}")
+	g.Expect(transform("APP_CLIENT_SECRET", secret)).To(Equal("spring-client-secret"))
+
+	// invalid rules
+	viper.Set(env.SecretKeyRenameRegex, "^APP_")
+	_, err := transform("APP_CLIENT_ID", secret)
+	g.Expect(err).To(MatchError("invalid key rename rule \"^APP_\"; expecting REGEX=REPLACEMENT"))
+}
+
+func TestCurrentRenames(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretKeyRenameTable, "FOO=BAR")
+	viper.Set(env.SecretKeyRenameRegex, "^APP_=")
+
+	// parsed once per configuration
+	renames, err := currentRenames()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(renames.table).To(Equal(map[string]string{"FOO": "BAR"}))
+	g.Expect(renames.rules).To(HaveLen(1))
+	g.Expect(currentRenames()).To(BeIdenticalTo(renames))
+
+	viper.Set(env.SecretKeyRenameRegex, "^APP_=;_ID$=")
+	changed, err := currentRenames()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(changed.rules).To(HaveLen(2))
+}
+
+func TestValidateKeyTransformation(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	g.Expect(ValidateKeyTransformation()).To(Succeed())
+
+	viper.Set(env.SecretKeyTransformation, "ToSnake,ToCobra")
+	g.Expect(ValidateKeyTransformation()).To(MatchError("unknown key transformation function \"ToCobra\""))
+
+	viper.Set(env.SecretKeyTransformation, "ToSnake")
+	viper.Set(env.SecretKeyRenameRegex, "[=")
+	g.Expect(ValidateKeyTransformation()).To(MatchError("invalid key rename rule \"[=\": error parsing regexp: missing closing ]: `[`"))
+
+	viper.Set(env.SecretKeyRenameRegex, "")
+	viper.Set(env.SecretKeyRenameTable, "APP_ID=id, APP_SECRET")
+	g.Expect(ValidateKeyTransformation()).To(MatchError("invalid key rename table entry \"APP_SECRET\"; expecting OLD=NEW"))
+
+	viper.Set(env.SecretKeyRenameTable, "")
+	viper.Set(env.SecretKeyRenameTemplate, "{{.Kye}}")
+	g.Expect(ValidateKeyTransformation()).To(MatchError(ContainSubstring("can't evaluate field Kye")))
+
+	viper.Set(env.SecretKeyRenameTemplate, "{{.Key}}-{{.Name}}")
+	g.Expect(ValidateKeyTransformation()).To(Succeed())
+}
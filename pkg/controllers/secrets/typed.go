@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	intPattern   = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*)$`)
+	floatPattern = regexp.MustCompile(`^[-+]?((0|[1-9][0-9]*)(\.[0-9]*)?|\.[0-9]+)([eE][-+]?[0-9]+)?$`)
+)
+
+// valueTypes contains the conversion functions for all types supported by the type annotation (see
+// [env.TypeAnnotation]).
+var valueTypes = map[string]func(string) (interface{}, error){
+	"string": func(value string) (interface{}, error) {
+		return value, nil
+	},
+	"int": func(value string) (interface{}, error) {
+		return strconv.Atoi(strings.TrimSpace(value))
+	},
+	"float": func(value string) (interface{}, error) {
+		return strconv.ParseFloat(strings.TrimSpace(value), 64)
+	},
+	"bool": func(value string) (interface{}, error) {
+		return strconv.ParseBool(strings.TrimSpace(value))
+	},
+}
+
+// typedValue returns the value of the given (K8s secret) key. The value is converted to the type given by the type
+// annotation of the secret, or inferred, if the key is configured to be typed (see [env.SecretKeyTyped]). All other
+// values are returned as string.
+func typedValue(secret *corev1.Secret, key string) (interface{}, error) {
+	value := string(secret.Data[key])
+
+	hints, err := typeHints(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if hint, ok := hints[key]; ok {
+		typed, err := valueTypes[hint](value)
+		if err != nil {
+			return nil, &contentError{source: fmt.Sprintf("key %q", key), err: fmt.Errorf("expecting %s: %w", hint, err)}
+		}
+		return typed, nil
+	}
+
+	if matchAny(patterns(env.SecretKeyTyped), key) {
+		return inferType(value), nil
+	}
+	return value, nil
+}
+
+// inferType converts the given value to int, float, bool or null, if it looks like one. Numbers with leading zeros
+// (e.g. zip codes) and numbers exceeding the int range are kept as string. Floats are only inferred, if they are
+// written exactly like they are formatted, so versions like "1.10" or "1.0" are kept as string.
+func inferType(value string) interface{} {
+	switch {
+	case intPattern.MatchString(value):
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+		return value
+	case floatPattern.MatchString(value):
+		if f, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(f, 0) && formatsAs(f, value) {
+			return f
+		}
+		return value
+	}
+
+	switch value {
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case "null", "Null", "NULL", "~":
+		return nil
+	}
+	return value
+}
+
+// typeHints parses the comma separated KEY=TYPE pairs of the type annotation of the given secret.
+func typeHints(secret *corev1.Secret) (map[string]string, error) {
+	hints := make(map[string]string)
+	for _, entry := range env.SplitList(secret.Annotations[env.TypeAnnotation]) {
+		key, hint, ok := strings.Cut(entry, "=")
+		key, hint = strings.TrimSpace(key), strings.TrimSpace(hint)
+		if _, known := valueTypes[hint]; !ok || key == "" || !known {
+			return nil, &contentError{
+				source: fmt.Sprintf("annotation %q", env.TypeAnnotation),
+				err:    fmt.Errorf("invalid entry %q; expecting KEY=[string|int|float|bool]", entry),
+			}
+		}
+		hints[key] = hint
+	}
+	return hints, nil
+}
+
+// formatsAs returns true, if the float is formatted as the given value, so converting it does not change the value.
+func formatsAs(f float64, value string) bool {
+	return strconv.FormatFloat(f, 'f', -1, 64) == value || strconv.FormatFloat(f, 'g', -1, 64) == value
+}
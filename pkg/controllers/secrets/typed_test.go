@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInferType(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := map[string]interface{}{
+		"8080":                 8080,
+		"-1":                   -1,
+		"0":                    0,
+		"1.5":                  1.5,
+		"0.25":                 0.25,
+		"-0.5":                 -0.5,
+		"1e+21":                1e21,
+		"1e3":                  "1e3",
+		"1.0":                  "1.0",
+		"1.10":                 "1.10",
+		".5":                   ".5",
+		"true":                 true,
+		"FALSE":                false,
+		"":                     "",
+		"01234":                "01234",
+		"99999999999999999999": "99999999999999999999",
+		"1.2.3":                "1.2.3",
+		"yes":                  "yes",
+		"NaN":                  "NaN",
+		"secret":               "secret",
+	}
+
+	for value, expected := range tests {
+		g.Expect(inferType(value)).To(Equal(expected), value)
+	}
+
+	g.Expect(inferType("null")).To(BeNil())
+	g.Expect(inferType("~")).To(BeNil())
+}
+
+func TestReadSecretContent_typed(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "1-2-3-4",
+		},
+		Data: map[string][]byte{
+			"PORT":    []byte("8080"),
+			"DEBUG":   []byte("true"),
+			"VERSION": []byte("1.0"),
+		},
+	}
+
+	// strings by default
+	result, err := readSecretContent(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(map[interface{}]interface{}{"PORT": "8080", "DEBUG": "true", "VERSION": "1.0"}))
+
+	// inferred types for all keys
+	viper.Set(env.SecretKeyTyped, "*")
+	result, err = readSecretContent(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(map[interface{}]interface{}{"PORT": 8080, "DEBUG": true, "VERSION": "1.0"}))
+
+	// type hints take precedence
+	secret.Annotations = map[string]string{env.TypeAnnotation: "VERSION=string, PORT=float"}
+	result, err = readSecretContent(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(map[interface{}]interface{}{"PORT": 8080.0, "DEBUG": true, "VERSION": "1.0"}))
+
+	// type hints apply to mapped keys as well, even if not configured to be typed
+	viper.Set(env.SecretKeyTyped, "")
+	viper.Set(env.SecretContentMapping, "PORT=server.port")
+	secret.Annotations = map[string]string{env.TypeAnnotation: "PORT=int"}
+	result, err = readSecretContent(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(map[interface{}]interface{}{"server": map[interface{}]interface{}{"port": 8080}}))
+
+	// values not matching their type hint are reported per secret
+	secret.Annotations = map[string]string{env.TypeAnnotation: "PORT=bool"}
+	_, err = readSecretContent(secret)
+	g.Expect(err).To(MatchError(ContainSubstring(`invalid content of key "PORT": expecting bool`)))
+	g.Expect(isContentError(err)).To(BeTrue())
+
+	// as well as unknown types
+	secret.Annotations = map[string]string{env.TypeAnnotation: "PORT=number"}
+	_, err = readSecretContent(secret)
+	g.Expect(err).To(MatchError(ContainSubstring(`invalid entry "PORT=number"`)))
+	g.Expect(isContentError(err)).To(BeTrue())
+}
@@ -0,0 +1,93 @@
+package env
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// process level settings, which cannot be changed by pod annotations. The environment available in conditions is
+// allow-listed by whoever deploys the sidecar only.
+var unconfigurable = []string{"pod.", "port.", "log.", "webhook.", "mapping.", Oneshot, SecretConditionEnv}
+
+// ApplyAnnotations overrides the configuration with the values of all annotations prefixed by [ConfigAnnotationPrefix].
+// Environment variables and flags act as defaults. See [ConfigFromAnnotations].
+func ApplyAnnotations(annotations map[string]string) error {
+	config, err := ConfigFromAnnotations(annotations)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range config {
+		viper.Set(key, value)
+	}
+	return nil
+}
+
+// ConfigFromAnnotations returns the configuration keys and values of all annotations prefixed by
+// [ConfigAnnotationPrefix]. Returns an error for unknown configuration keys, so typos do not go unnoticed.
+func ConfigFromAnnotations(annotations map[string]string) (map[string]string, error) {
+	known := viper.AllKeys()
+
+	config := make(map[string]string)
+	for annotation, value := range annotations {
+		key, ok := strings.CutPrefix(annotation, ConfigAnnotationPrefix)
+		if !ok {
+			continue
+		}
+
+		if !slices.Contains(known, key) || slices.ContainsFunc(unconfigurable, func(prefix string) bool {
+			return strings.HasPrefix(key, prefix)
+		}) {
+			return nil, fmt.Errorf("invalid configuration annotation %q: unknown or unsupported key %q", annotation, key)
+		}
+		config[key] = value
+	}
+	return config, nil
+}
+
+// EnvName returns the name of the environment variable setting the given configuration key, e.g. 'SECRET_FILE_SINGLE'
+// for 'secret.file.single'.
+func EnvName(key string) string {
+	return strings.ToUpper(replacer.Replace(key))
+}
+
+// LogConfiguration logs the effective configuration. Values, which might contain credentials, are redacted.
+func LogConfiguration() {
+	keys := viper.AllKeys()
+	slices.Sort(keys)
+
+	attrs := make([]any, 0, len(keys)*2)
+	for _, key := range keys {
+		attrs = append(attrs, key, redact(key, viper.GetString(key)))
+	}
+	slog.Info("effective configuration", attrs...)
+}
+
+// redact removes credentials from the given configuration value. The callback body is redacted completely, the callback
+// URL keeps everything but the password and query.
+func redact(key, value string) string {
+	if value == "" {
+		return value
+	}
+
+	switch key {
+	case CallbackURL:
+		u, err := url.Parse(value)
+		if err != nil {
+			return "REDACTED"
+		}
+		if u.RawQuery != "" {
+			// query parameters often contain tokens
+			u.RawQuery = "REDACTED"
+		}
+		return u.Redacted()
+	case CallbackBody:
+		return "REDACTED"
+	}
+	return value
+}
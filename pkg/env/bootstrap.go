@@ -3,6 +3,7 @@ package env
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -10,37 +11,85 @@ import (
 )
 
 func Bootstrap(rootCmd *cobra.Command) {
+	// flags shared with all sub commands
+	rootCmd.PersistentFlags().Bool(LogJson, DefaultLogJson, "output logs in JSON format")
+	rootCmd.PersistentFlags().String(LogLevel, DefaultLogLevel.String(), "log level")
+	rootCmd.PersistentFlags().String(SecretLabelSelector, "", "secret labels to consider")
+	rootCmd.PersistentFlags().String(SecretNameSelector, "", "secret name pattern to consider")
+	rootCmd.PersistentFlags().String(SecretAnnotationSelector, "", "secret annotations to consider, in label selector syntax")
+	rootCmd.PersistentFlags().String(SecretTypeSelector, "", "comma separated list of secret types to consider")
+	rootCmd.PersistentFlags().String(SecretNamespaceSelector, "", "comma separated list of namespaces to consider")
+	rootCmd.PersistentFlags().String(SecretPollNames, "", "comma separated list of secret names (NAME or NAMESPACE/NAME) to poll instead of watching secrets")
+	rootCmd.PersistentFlags().String(FinalizerSweepNamespace, "", "comma separated list of namespaces the sidecar pods run in")
+
 	rootCmd.Flags().String(PodName, "", "the pods name")
+	rootCmd.Flags().String(MappingName, "", "name of the SecretFileMapping resource configuring the sidecar, re-applied on change")
+	rootCmd.Flags().String(MappingNamespace, "", "namespace of the SecretFileMapping resource (default the pods namespace)")
+	rootCmd.Flags().Bool(Oneshot, false, "set to 'true' to process all matching secrets once and exit, e.g. in an init container")
+	rootCmd.Flags().String(PodNamespace, "", "the pods namespace; if set, the configuration is read from the pods annotations, too")
 	rootCmd.Flags().Uint32(PortHealthcheck, DefaultPortHealthcheck, "port the health endpoints bind to")
 	rootCmd.Flags().Uint32(PortMetrics, DefaultPortMetrics, "port the controller runtime metrics endpoint binds to")
 	rootCmd.Flags().Uint32(PortDebug, DefaultPortDebug, "port the go debug information are present on")
-	rootCmd.Flags().Bool(LogJson, DefaultLogJson, "output logs in JSON format")
-	rootCmd.Flags().String(LogLevel, DefaultLogLevel.String(), "log level")
-	rootCmd.Flags().String(SecretLabelSelector, "", "secret labels to consider")
-	rootCmd.Flags().String(SecretNameSelector, "", "secret name pattern to consider")
+	rootCmd.Flags().String(SecretNamespaceLabelSelector, "", "namespace labels to consider, re-evaluated on namespace changes")
+	rootCmd.Flags().String(SecretNamespaceNameSelector, "", "namespace name pattern to consider, re-evaluated on namespace changes")
+	rootCmd.Flags().Duration(SecretPollInterval, DefaultSecretPollInterval, "interval between two polls of the secrets given by "+SecretPollNames)
+	rootCmd.Flags().Float64(SecretPollJitter, DefaultSecretPollJitter, "maximum factor of the poll interval, by which each poll is delayed randomly")
+	rootCmd.Flags().String(SecretCondition, "", "template deciding per secret, whether it is considered; content of secrets no longer matching is removed")
+	rootCmd.Flags().String(SecretConditionEnv, "", "comma separated list of environment variables available in the condition via '.Env'")
 	rootCmd.Flags().String(SecretContentSelector, "", "secret content path to copy")
-	rootCmd.Flags().String(SecretKeyTransformation, "", "transformation function for all secret keys")
+	rootCmd.Flags().String(SecretContentMapping, "", "semicolon separated list of KEY=PROPERTY_PATH entries to copy")
+	rootCmd.Flags().String(TemplateFile, "", "template file rendered with all matching secrets into the target file")
+	rootCmd.Flags().String(TemplateDir, "", "directory containing shared templates, reloaded on change")
+	rootCmd.Flags().Bool(TemplateStrict, DefaultTemplateStrict, "set to 'true' to fail templates referencing missing keys instead of rendering '<no value>'")
+	rootCmd.Flags().String(SecretKeyInclude, "", "comma separated list of glob patterns for secret keys to copy")
+	rootCmd.Flags().String(SecretKeyExclude, "", "comma separated list of glob patterns for secret keys not to copy")
+	rootCmd.Flags().String(SecretKeyStructured, "", "comma separated list of glob patterns for secret keys containing YAML or JSON documents")
+	rootCmd.Flags().String(SecretKeyTyped, "", "comma separated list of glob patterns for secret keys, whose values are converted to int, float, bool or null")
+	rootCmd.Flags().String(SecretKeySeparator, "", "separator for splitting secret keys into nested properties")
+	rootCmd.Flags().String(SecretKeyTransformation, "", "comma separated list of transformation functions for all secret keys")
+	rootCmd.Flags().String(SecretKeyRenameTable, "", "comma separated list of OLD=NEW pairs for renaming secret keys")
+	rootCmd.Flags().String(SecretKeyRenameRegex, "", "semicolon separated list of REGEX=REPLACEMENT rules for renaming secret keys")
+	rootCmd.Flags().String(SecretKeyRenameTemplate, "", "template for renaming secret keys, with access to the key via '.Key'")
 	rootCmd.Flags().Bool(SecretDeletionWatch, false, "set to 'true' if secret deletion should be watched and therefore their content needs to be dropped from FS")
 	rootCmd.Flags().Bool(SecretFileSingle, false, "set to 'true' if each secret key should get it's own file")
+	rootCmd.Flags().String(SecretFileRoot, "", "directory all written files are confined to, paths outside of it are rejected")
 	rootCmd.Flags().String(SecretFileNamePattern, "", "target filename pattern")
 	rootCmd.Flags().String(SecretFilePropertyPattern, "", "base property path in target file")
+	rootCmd.Flags().String(SecretFileListIdentity, "", "template for the identity of the list element each secret is written as at the property path")
+	rootCmd.Flags().String(SecretFileListKey, DefaultSecretFileListKey, "property of each list element containing its identity")
 	rootCmd.Flags().String(CallbackURL, "", "URL to call with GET request for successful file updates")
 	rootCmd.Flags().String(CallbackMethod, http.MethodGet, "method for callback URL, sent on file updates")
 	rootCmd.Flags().String(CallbackBody, "", "body sent with callback on file updates")
 	rootCmd.Flags().String(CallbackContentType, "application/json", "Content-Type header of callback requests")
+	rootCmd.Flags().Duration(FinalizerSweepInterval, time.Duration(0), "interval for removing finalizers of no longer existing pods, 0 disables the sweep")
 
+	// the file name pattern is validated on startup, as it might be set by pod annotations
 	rootCmd.MarkFlagRequired(PodName)
-	rootCmd.MarkFlagRequired(SecretFileNamePattern)
 
+	viper.BindPFlags(rootCmd.PersistentFlags())
 	viper.BindPFlags(rootCmd.Flags())
 
 	cobra.OnInitialize(unmarkRequired(rootCmd))
 }
 
+// BootstrapWebhook registers the flags of the admission webhook command.
+func BootstrapWebhook(webhookCmd *cobra.Command) {
+	webhookCmd.Flags().Int(WebhookPort, DefaultWebhookPort, "port the admission webhook serves on")
+	webhookCmd.Flags().String(WebhookCertDir, "", "directory containing tls.crt and tls.key of the admission webhook")
+	webhookCmd.Flags().String(WebhookImage, "", "image of the injected sidecar")
+
+	webhookCmd.MarkFlagRequired(WebhookImage)
+
+	viper.BindPFlags(webhookCmd.Flags())
+
+	cobra.OnInitialize(unmarkRequired(webhookCmd))
+}
+
+// Allow flags containing dashes / dots to be set by environment variables which use underscores instead of dashes /
+// dots.
+var replacer = strings.NewReplacer("-", "_", ".", "_")
+
 func initConfig() {
-	// Allow flags containing dashes / dots to be set by environment variables which use underscores instead of dashes /
-	// dots.
-	replacer := strings.NewReplacer("-", "_", ".", "_")
 	viper.SetEnvKeyReplacer(replacer)
 }
 
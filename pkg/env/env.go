@@ -2,10 +2,40 @@ package env
 
 import (
 	"log/slog"
+	"time"
 )
 
 const (
 	PodName = "pod.name"
+	// namespace of the pod, enables reading the configuration from the annotations of the pod
+	PodNamespace = "pod.namespace"
+
+	// prefix of pod annotations overriding the configuration, followed by the configuration key, e.g.
+	// 'config.secret-file-provider.jaconi.io/secret.selector.label'
+	ConfigAnnotationPrefix = "config.secret-file-provider.jaconi.io/"
+
+	// prefix of all finalizers added by the sidecar, followed by the (tail of the) pod name
+	FinalizerPrefix = "jaconi.io/secret-file-provider-"
+
+	// name of the SecretFileMapping resource configuring the sidecar
+	MappingName = "mapping.name"
+	// namespace of the SecretFileMapping resource, defaults to the namespace of the pod
+	MappingNamespace = "mapping.namespace"
+
+	// true, if all matching secrets should be processed once, before exiting
+	Oneshot = "oneshot"
+
+	// port the admission webhook serves on
+	WebhookPort = "webhook.port"
+	// directory containing the serving certificate (tls.crt) and key (tls.key) of the admission webhook
+	WebhookCertDir = "webhook.cert-dir"
+	// image of the injected sidecar
+	WebhookImage = "webhook.image"
+
+	// annotation of a pod requesting the injection of the sidecar
+	InjectAnnotation = "secret-file-provider.jaconi.io/inject"
+	// annotation of a pod with the path the shared volume is mounted at
+	MountPathAnnotation = "secret-file-provider.jaconi.io/mount-path"
 
 	PortHealthcheck = "port.healthcheck"
 	PortMetrics     = "port.metrics"
@@ -15,23 +45,84 @@ const (
 	SecretLabelSelector = "secret.selector.label"
 	// K8s secret name selector
 	SecretNameSelector = "secret.selector.name"
+	// K8s label selector applied to the annotations of secrets
+	SecretAnnotationSelector = "secret.selector.annotation"
+	// comma separated list of K8s secret types, e.g. 'kubernetes.io/tls'
+	SecretTypeSelector = "secret.selector.type"
+	// template deciding per secret, whether it is considered
+	SecretCondition = "secret.selector.condition"
+	// comma separated list of environment variables available in the condition template
+	SecretConditionEnv = "secret.selector.condition-env"
 	// K8s namespace selector
 	SecretNamespaceSelector = "secret.selector.namespace"
+	// K8s label selector for namespaces
+	SecretNamespaceLabelSelector = "secret.selector.namespace-label"
+	// namespace name selector in regex format
+	SecretNamespaceNameSelector = "secret.selector.namespace-name"
+	// comma separated list of secret names (NAME or NAMESPACE/NAME), which are polled instead of watched
+	SecretPollNames = "secret.poll.names"
+	// interval between two polls of the secrets
+	SecretPollInterval = "secret.poll.interval"
+	// maximum factor of the poll interval, by which each poll is delayed randomly
+	SecretPollJitter = "secret.poll.jitter"
+
 	// read only a specific field of the whole secret data
 	SecretContentSelector = "secret.selector.content"
+	// semicolon separated list of KEY=PROPERTY_PATH entries, placing single keys at templated property paths
+	SecretContentMapping = "secret.selector.mapping"
 
 	// true, if all secrets should be contained by a single file
 	SecretFileSingle = "secret.file.single"
+	// directory all written files and directories are confined to
+	SecretFileRoot = "secret.file.root"
 	// pattern for secret file names
 	SecretFileNamePattern = "secret.file.name.pattern"
 	// pattern for a secret property prefix
 	SecretFilePropertyPattern = "secret.file.property.pattern"
+	// template for the identity of the list element each secret is written as, enables writing a list at the property path
+	SecretFileListIdentity = "secret.file.list.identity"
+	// property of each list element containing its identity
+	SecretFileListKey = "secret.file.list.key"
+
+	// template file rendered with all matching secrets, replaces the per secret content mapping if set
+	TemplateFile = "template.file"
+	// directory containing shared templates, available in all templates by their file name
+	TemplateDir = "template.dir"
+	// true, if templates should fail on missing map keys instead of rendering "<no value>"
+	TemplateStrict = "template.strict"
+
+	// comma separated list of glob patterns for (K8s secret) keys to copy
+	SecretKeyInclude = "secret.key.include"
+	// comma separated list of glob patterns for (K8s secret) keys not to copy
+	SecretKeyExclude = "secret.key.exclude"
 
-	// transformation function for (K8s secret) keys
+	// comma separated list of glob patterns for (K8s secret) keys containing YAML or JSON documents
+	SecretKeyStructured = "secret.key.structured"
+
+	// comma separated list of glob patterns for (K8s secret) keys, whose values are converted to int, float, bool or null
+	SecretKeyTyped = "secret.key.typed"
+	// annotation of a secret with comma separated KEY=TYPE pairs, setting the type of single values explicitly
+	TypeAnnotation = "secret-file-provider.jaconi.io/types"
+
+	// separator for splitting (K8s secret) keys into nested properties, e.g. '.' or '__'
+	SecretKeySeparator = "secret.key.separator"
+
+	// comma separated list of transformation functions for (K8s secret) keys
 	SecretKeyTransformation = "secret.key.transformation"
+	// comma separated list of OLD=NEW pairs for renaming (K8s secret) keys
+	SecretKeyRenameTable = "secret.key.rename.table"
+	// semicolon separated list of REGEX=REPLACEMENT rules for renaming (K8s secret) keys
+	SecretKeyRenameRegex = "secret.key.rename.regex"
+	// template for renaming (K8s secret) keys
+	SecretKeyRenameTemplate = "secret.key.rename.template"
 
 	SecretDeletionWatch = "secret.deletion.watch"
 
+	// interval for removing finalizers of no longer existing pods, 0 disables the background sweep
+	FinalizerSweepInterval = "finalizer.sweep.interval"
+	// comma separated list of namespaces the sidecar pods run in
+	FinalizerSweepNamespace = "finalizer.sweep.namespace"
+
 	CallbackMethod      = "callback.method"
 	CallbackURL         = "callback.url"
 	CallbackBody        = "callback.body"
@@ -46,4 +137,14 @@ const (
 
 	DefaultLogJson  = false
 	DefaultLogLevel = slog.LevelInfo
+
+	DefaultTemplateStrict = false
+
+	DefaultSecretFileListKey = "name"
+
+	DefaultWebhookPort = 9443
+	DefaultMountPath   = "/etc/secret-file-provider"
+
+	DefaultSecretPollInterval = 30 * time.Second
+	DefaultSecretPollJitter   = 0.2
 )
@@ -6,28 +6,61 @@ import (
 	"github.com/spf13/viper"
 )
 
-// GetSingleNamespace will return the name of a single namespace, if set. This will return
-// an empty string if either no namespace or multiple ones are actively selected.
-func GetSingleNamespace() string {
-	ns := viper.GetString(SecretNamespaceSelector)
-	if ns == "" || strings.Contains(ns, ",") {
-		// multiple namespaces selected, return empty string
-		return ""
-	}
-	return ns
+// GetNamespaces returns all selected namespaces. This will return an empty slice if no namespace is selected, meaning
+// all namespaces are considered.
+func GetNamespaces() []string {
+	return SplitList(viper.GetString(SecretNamespaceSelector))
+}
+
+// GetSecretTypes returns all selected secret types. This will return an empty slice if no type is selected, meaning
+// secrets of all types are considered.
+func GetSecretTypes() []string {
+	return SplitList(viper.GetString(SecretTypeSelector))
+}
+
+// GetPollNames returns the names of all polled secrets. This will return an empty slice if no secret is polled, meaning
+// secrets are watched.
+func GetPollNames() []string {
+	return SplitList(viper.GetString(SecretPollNames))
+}
+
+// GetConditionEnv returns the names of all environment variables available in the condition template. This will return
+// an empty slice if no variable is allowed.
+func GetConditionEnv() []string {
+	return SplitList(viper.GetString(SecretConditionEnv))
+}
+
+// GetFinalizerSweepNamespaces returns the namespaces sidecar pods are looked up in by the finalizer sweep. This will
+// return an empty slice if no namespace is configured, meaning pods in all namespaces are considered.
+func GetFinalizerSweepNamespaces() []string {
+	return SplitList(viper.GetString(FinalizerSweepNamespace))
 }
 
 // GetFinalizer returns the finalizer name. The finalizer name depends on the pod name.
 func GetFinalizer() string {
-	prefix := "jaconi.io/secret-file-provider-"
-	pod := viper.GetString(PodName)
+	return GetFinalizerFor(viper.GetString(PodName))
+}
 
+// GetFinalizerFor returns the finalizer name a sidecar running in the pod with the given name uses.
+func GetFinalizerFor(pod string) string {
 	// Kubernetes limits finalizer names to 63 characters. We use the tail of the pod name, as it contains the hash and
 	// is therefore less prone to collisions.
-	if len(prefix)+len(pod) > 63 {
-		maxPodLen := 63 - len(prefix)
-		return prefix + pod[len(pod)-maxPodLen:]
+	if len(FinalizerPrefix)+len(pod) > 63 {
+		maxPodLen := 63 - len(FinalizerPrefix)
+		return FinalizerPrefix + pod[len(pod)-maxPodLen:]
 	}
 
-	return prefix + pod
+	return FinalizerPrefix + pod
+}
+
+// SplitList splits a comma separated list, dropping empty elements and surrounding whitespace.
+func SplitList(list string) []string {
+	var result []string
+	for _, element := range strings.Split(list, ",") {
+		element = strings.TrimSpace(element)
+		if element != "" {
+			result = append(result, element)
+		}
+	}
+	return result
 }
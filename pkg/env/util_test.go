@@ -6,6 +6,7 @@ import (
 	. "github.com/jaconi-io/secret-file-provider/pkg/env"
 
 	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
@@ -18,3 +19,34 @@ func TestGetFinalizer(t *testing.T) {
 	g.Expect(GetFinalizer()).To(HaveLen(63))
 	g.Expect(GetFinalizer()).To(Equal("jaconi.io/secret-file-provider-engthy-pod-name-6d98ccb7dd-c8zr8"))
 }
+
+func TestGetNamespaces(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	g.Expect(GetNamespaces()).To(BeEmpty())
+
+	viper.Set(SecretNamespaceSelector, "foo")
+	g.Expect(GetNamespaces()).To(Equal([]string{"foo"}))
+
+	viper.Set(SecretNamespaceSelector, "foo, bar,")
+	g.Expect(GetNamespaces()).To(Equal([]string{"foo", "bar"}))
+}
+
+func TestSplitList(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(SplitList("")).To(BeEmpty())
+	g.Expect(SplitList(" , ,")).To(BeEmpty())
+	g.Expect(SplitList("a, b ,,c")).To(Equal([]string{"a", "b", "c"}))
+}
+
+func TestBootstrapTemplateStrict(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	Bootstrap(&cobra.Command{Use: "test"})
+
+	// existing installations keep rendering missing keys as '<no value>'
+	g.Expect(viper.GetBool(TemplateStrict)).To(BeFalse())
+}
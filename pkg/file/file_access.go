@@ -2,8 +2,10 @@ package file
 
 import (
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/jaconi-io/secret-file-provider/pkg/env"
 	"github.com/jaconi-io/secret-file-provider/pkg/templates"
@@ -14,21 +16,32 @@ import (
 )
 
 // Name will return either the filename of a single file to contain the secret information or the directory path, where
-// all files should be stored in.
+// all files should be stored in. The rendered path is confined to the output root (see [Confine]).
 func Name(secret *corev1.Secret) (string, error) {
-	return templates.Render(viper.GetString(env.SecretFileNamePattern), secret)
+	name, err := templates.Render(viper.GetString(env.SecretFileNamePattern), secret)
+	if err != nil {
+		return "", err
+	}
+	return Confine(name)
 }
 
 // ReadAll secret contents of all existing files for the secret.
 func ReadAll(filename string) (map[interface{}]interface{}, error) {
+	root, name, err := openRoot(filename, false)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
 	if viper.GetBool(env.SecretFileSingle) {
-		return readMultipleFiles(filename)
+		return readMultipleFiles(root, name)
 	}
 
-	f, err := os.Open(filename)
+	f, err := root.Open(name)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 
 	content := make(map[interface{}]interface{})
 	err = yaml.NewDecoder(f).Decode(content)
@@ -39,17 +52,32 @@ func ReadAll(filename string) (map[interface{}]interface{}, error) {
 	return content, nil
 }
 
-func readMultipleFiles(dir string) (map[interface{}]interface{}, error) {
+// readMultipleFiles reads the directory tree into nested maps: directories become maps, files their string content.
+func readMultipleFiles(root *os.Root, dir string) (map[interface{}]interface{}, error) {
 	result := make(map[interface{}]interface{})
 
-	files, err := os.ReadDir(dir)
+	d, err := root.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := d.ReadDir(-1)
+	d.Close()
 	if err != nil {
 		return nil, err
 	}
 
 	for _, file := range files {
 		path := filepath.Join(dir, file.Name())
-		bytes, err := os.ReadFile(path)
+		if file.IsDir() {
+			content, err := readMultipleFiles(root, path)
+			if err != nil {
+				return nil, err
+			}
+			result[file.Name()] = content
+			continue
+		}
+
+		bytes, err := root.ReadFile(path)
 		if err != nil {
 			return nil, err
 		}
@@ -63,18 +91,25 @@ func readMultipleFiles(dir string) (map[interface{}]interface{}, error) {
 // WriteAll content either into a single file with the given identifier or into multiple ones under a directory with
 // the given name.
 func WriteAll(filename string, content map[interface{}]interface{}) error {
+	root, name, err := openRoot(filename, true)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
 	if viper.GetBool(env.SecretFileSingle) {
-		return writeMultipleFiles(filename, content)
+		return writeMultipleFiles(root, name, content)
 	}
 
-	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+	if err := root.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
 		return err
 	}
 
-	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	f, err := root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 
 	err = yaml.NewEncoder(f).Encode(content)
 	if err != nil {
@@ -84,20 +119,137 @@ func WriteAll(filename string, content map[interface{}]interface{}) error {
 	return nil
 }
 
-func writeMultipleFiles(filename string, content map[interface{}]interface{}) error {
-	// TODO handle delete file case!
-	if err := os.MkdirAll(filename, os.ModePerm); err != nil {
+// writeMultipleFiles writes the content as directory tree: nested maps become directories, all other values files
+// containing the value (see [leaf]). Files and directories not contained in the content (any longer) are removed.
+func writeMultipleFiles(root *os.Root, dir string, content map[interface{}]interface{}) error {
+	if err := root.MkdirAll(dir, os.ModePerm); err != nil {
 		return err
 	}
 
+	names := make(map[string]bool, len(content))
 	for k, v := range content {
-		file := fmt.Sprintf("%v", k)
-		content := fmt.Sprintf("%v", v)
-		err := os.WriteFile(filepath.Join(filename, file), []byte(content), 0644)
+		// keys must neither leave the directory nor follow symlinks out of it
+		name := fmt.Sprintf("%v", k)
+		if _, err := child(filepath.Join(root.Name(), dir), name); err != nil {
+			return err
+		}
+		path := filepath.Join(dir, name)
+		names[name] = true
+
+		info, err := root.Lstat(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if m, ok := v.(map[interface{}]interface{}); ok {
+			if info != nil && !info.IsDir() {
+				// a value turned into a nested map
+				if err := root.Remove(path); err != nil {
+					return err
+				}
+			}
+			if err := writeMultipleFiles(root, path, m); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if info != nil && info.IsDir() {
+			// a nested map turned into a value
+			if err := root.RemoveAll(path); err != nil {
+				return err
+			}
+		}
+		data, err := leaf(v)
 		if err != nil {
+			return fmt.Errorf("invalid secret content for %s: %w", filepath.Join(root.Name(), path), err)
+		}
+		if err := root.WriteFile(path, data, 0644); err != nil {
 			return err
 		}
 	}
 
+	d, err := root.Open(dir)
+	if err != nil {
+		return err
+	}
+	files, err := d.ReadDir(-1)
+	d.Close()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if !names[file.Name()] {
+			if err := root.RemoveAll(filepath.Join(dir, file.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
+
+// leaf returns the file content of a single value: scalars as plain text, nil as empty file and all other values, like
+// lists, as YAML.
+func leaf(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(v), nil
+	case bool, int, int64, uint64, float64:
+		return []byte(fmt.Sprintf("%v", v)), nil
+	default:
+		return yaml.Marshal(v)
+	}
+}
+
+// WriteAtomic writes the content to the given file, replacing it atomically. Readers either see the previous or the new
+// content, but never a partially written file.
+func WriteAtomic(filename string, content []byte) error {
+	root, name, err := openRoot(filename, true)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	dir := filepath.Dir(name)
+	if err := root.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// The temporary file has to be on the same file system for the rename to be atomic.
+	tmp, tmpName, err := createTemp(root, dir, "."+filepath.Base(name)+".")
+	if err != nil {
+		return err
+	}
+	defer root.Remove(tmpName)
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return root.Rename(tmpName, name)
+}
+
+// createTemp creates a new temporary file within the directory of the root, like [os.CreateTemp]. Returns the file and
+// its name relative to the root.
+func createTemp(root *os.Root, dir string, prefix string) (*os.File, string, error) {
+	for range 10000 {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err := root.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		return f, name, err
+	}
+	return nil, "", &os.PathError{Op: "createtemp", Path: filepath.Join(root.Name(), dir, prefix+"*"), Err: os.ErrExist}
+}
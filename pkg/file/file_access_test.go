@@ -304,3 +304,110 @@ func TestWriteAllFilePerSecret(t *testing.T) {
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(string(b)).To(gomega.Equal(testString))
 }
+
+func TestWriteAllFilePerSecretNested(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	viper.Set(env.SecretFileSingle, true)
+	defer viper.Reset()
+
+	dir := t.TempDir()
+
+	err := WriteAll(dir, testData)
+	g.Expect(err).To(gomega.BeNil())
+
+	b, err := os.ReadFile(filepath.Join(dir, "foo", "bar", "baz"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(b)).To(gomega.Equal("42"))
+
+	// the directory tree is read back into nested maps
+	content, err := ReadAll(dir)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(content).To(gomega.Equal(map[interface{}]interface{}{
+		"foo": map[interface{}]interface{}{
+			"bar": map[interface{}]interface{}{
+				"baz": "42",
+			},
+			"oof": "7",
+		},
+	}))
+
+	// values and nested maps replace each other, entries no longer contained are removed
+	err = WriteAll(dir, map[interface{}]interface{}{
+		"foo": map[interface{}]interface{}{
+			"bar": "baz",
+			"oof": map[interface{}]interface{}{
+				"rab": "zab",
+			},
+		},
+	})
+	g.Expect(err).To(gomega.BeNil())
+
+	content, err = ReadAll(dir)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(content).To(gomega.Equal(map[interface{}]interface{}{
+		"foo": map[interface{}]interface{}{
+			"bar": "baz",
+			"oof": map[interface{}]interface{}{
+				"rab": "zab",
+			},
+		},
+	}))
+
+	err = WriteAll(dir, map[interface{}]interface{}{})
+	g.Expect(err).To(gomega.BeNil())
+
+	files, err := os.ReadDir(dir)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(files).To(gomega.BeEmpty())
+}
+
+func TestWriteAllFilePerSecretLeaves(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	viper.Set(env.SecretFileSingle, true)
+	defer viper.Reset()
+
+	dir := t.TempDir()
+
+	err := WriteAll(dir, map[interface{}]interface{}{
+		"empty": nil,
+		"flag":  true,
+		"ratio": 0.5,
+		"list":  []interface{}{"a", map[interface{}]interface{}{"b": 1}},
+	})
+	g.Expect(err).To(gomega.BeNil())
+
+	// scalars are written as plain text, nil as empty file and lists as YAML
+	content, err := ReadAll(dir)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(content).To(gomega.Equal(map[interface{}]interface{}{
+		"empty": "",
+		"flag":  "true",
+		"ratio": "0.5",
+		"list":  "- a\n- b: 1\n",
+	}))
+}
+
+func TestWriteAtomic(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	dir, err := os.MkdirTemp("", "foo")
+	g.Expect(err).To(gomega.BeNil())
+
+	filename := filepath.Join(dir, "bar", "baz")
+	err = WriteAtomic(filename, []byte(testString))
+	g.Expect(err).To(gomega.BeNil())
+
+	err = WriteAtomic(filename, []byte("foo"))
+	g.Expect(err).To(gomega.BeNil())
+
+	b, err := os.ReadFile(filename)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(b)).To(gomega.Equal("foo"))
+
+	// no temporary files are left behind
+	files, err := os.ReadDir(filepath.Dir(filename))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(files).To(gomega.HaveLen(1))
+}
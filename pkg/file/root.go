@@ -0,0 +1,147 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+
+	"github.com/spf13/viper"
+)
+
+// confinementError is caused by a path, which leaves the directory it is confined to, e.g. rendered from a label like
+// "../../etc". Retrying does not help, until the secret is changed.
+type confinementError struct {
+	path string
+	root string
+}
+
+func (e *confinementError) Error() string {
+	return fmt.Sprintf("path %q is outside of %q", e.path, e.root)
+}
+
+// IsConfinementError returns true, if the error is caused by a path leaving the directory it is confined to.
+func IsConfinementError(err error) bool {
+	var confinementErr *confinementError
+	return errors.As(err, &confinementErr)
+}
+
+// Confine returns the cleaned path, if it stays within the output root (see [outputRoot]). Relative paths are resolved
+// against the root. Symlinks are followed for the check, so links pointing outside of the root are rejected as well. If
+// there is no root, the cleaned path is returned as is.
+func Confine(path string) (string, error) {
+	root := outputRoot()
+	if root == "" {
+		return filepath.Clean(path), nil
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	return within(filepath.Clean(root), filepath.Clean(path))
+}
+
+// outputRoot returns the configured output root (see [env.SecretFileRoot]). If unset, it is the directory of the file
+// name pattern (see [env.SecretFileNamePattern]) before its first template action, so rendered values like "../../etc"
+// cannot leave it either. Static patterns are not rendered, so there is no root for them.
+func outputRoot() string {
+	if root := viper.GetString(env.SecretFileRoot); root != "" {
+		return root
+	}
+
+	pattern := viper.GetString(env.SecretFileNamePattern)
+	i := strings.Index(pattern, "{{")
+	if i < 0 {
+		return ""
+	}
+	prefix := pattern[:i]
+	if strings.HasSuffix(prefix, string(filepath.Separator)) {
+		return filepath.Clean(prefix)
+	}
+	return filepath.Dir(prefix)
+}
+
+// openRoot opens the directory, the given clean path has to stay in, as [os.Root]: the configured output root (see
+// [env.SecretFileRoot]) or the parent directory of the path, which is created if requested. All files are accessed via
+// the root, so symlinks cannot lead outside of it, even if changed after the path has been checked by [Confine].
+// Returns the root and the path relative to it.
+func openRoot(path string, create bool) (*os.Root, string, error) {
+	dir := viper.GetString(env.SecretFileRoot)
+	if dir == "" {
+		dir = filepath.Dir(path)
+	}
+	dir = filepath.Clean(dir)
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, "", &confinementError{path: path, root: dir}
+	}
+
+	if create {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return nil, "", err
+		}
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	return root, rel, nil
+}
+
+// child returns the path of the given name within the directory. The name has to be a single path element, which does
+// not resolve outside of the directory.
+func child(dir string, name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, filepath.Separator) {
+		return "", &confinementError{path: filepath.Join(dir, name), root: dir}
+	}
+	return within(dir, filepath.Join(dir, name))
+}
+
+// within returns the given path, if it resolves to a location within the root. Both have to be clean.
+func within(root string, path string) (string, error) {
+	resolvedRoot, err := resolve(root)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := resolve(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// a dangling symlink, which might point anywhere
+			return "", &confinementError{path: path, root: root}
+		}
+		return "", err
+	}
+
+	rel, err := filepath.Rel(resolvedRoot, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &confinementError{path: path, root: root}
+	}
+	return path, nil
+}
+
+// resolve evaluates all symlinks within the existing part of the clean path. The remaining part does not exist yet, so
+// it cannot contain any symlinks.
+func resolve(path string) (string, error) {
+	existing, rest := path, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			resolved, err := filepath.EvalSymlinks(existing)
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(resolved, rest), nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return path, nil
+		}
+		existing, rest = parent, filepath.Join(filepath.Base(existing), rest)
+	}
+}
@@ -0,0 +1,150 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfine(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer viper.Reset()
+
+	// without root, paths are only cleaned
+	g.Expect(Confine("/var/config/../secret.yaml")).To(gomega.Equal("/var/secret.yaml"))
+
+	root := t.TempDir()
+	viper.Set(env.SecretFileRoot, root)
+
+	g.Expect(Confine(filepath.Join(root, "foo", "bar.yaml"))).To(gomega.Equal(filepath.Join(root, "foo", "bar.yaml")))
+	g.Expect(Confine("foo/bar.yaml")).To(gomega.Equal(filepath.Join(root, "foo", "bar.yaml")))
+	g.Expect(Confine(root)).To(gomega.Equal(root))
+
+	for _, path := range []string{
+		filepath.Join(root, "..", "..", "etc"),
+		"../etc/passwd",
+		"/etc/passwd",
+		root + "-foo",
+	} {
+		_, err := Confine(path)
+		g.Expect(IsConfinementError(err)).To(gomega.BeTrue(), path)
+	}
+}
+
+func TestConfineSymlink(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer viper.Reset()
+
+	root, outside := t.TempDir(), t.TempDir()
+	viper.Set(env.SecretFileRoot, root)
+
+	g.Expect(os.Mkdir(filepath.Join(root, "inside"), os.ModePerm)).To(gomega.Succeed())
+	g.Expect(os.Symlink(filepath.Join(root, "inside"), filepath.Join(root, "in"))).To(gomega.Succeed())
+	g.Expect(os.Symlink(outside, filepath.Join(root, "out"))).To(gomega.Succeed())
+	g.Expect(os.Symlink(filepath.Join(outside, "missing"), filepath.Join(root, "dangling"))).To(gomega.Succeed())
+
+	g.Expect(Confine(filepath.Join(root, "in", "secret.yaml"))).To(gomega.Equal(filepath.Join(root, "in", "secret.yaml")))
+
+	_, err := Confine(filepath.Join(root, "out", "secret.yaml"))
+	g.Expect(IsConfinementError(err)).To(gomega.BeTrue())
+
+	_, err = Confine(filepath.Join(root, "dangling"))
+	g.Expect(IsConfinementError(err)).To(gomega.BeTrue())
+}
+
+func TestNameOutsideRoot(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer viper.Reset()
+
+	root := t.TempDir()
+	viper.Set(env.SecretFileRoot, root)
+	viper.Set(env.SecretFileNamePattern, root+"/{{.ObjectMeta.Labels.dir}}/secret.yaml")
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"dir": "foo"}}}
+	g.Expect(Name(secret)).To(gomega.Equal(filepath.Join(root, "foo", "secret.yaml")))
+
+	secret.Labels["dir"] = "../../etc"
+	_, err := Name(secret)
+	g.Expect(IsConfinementError(err)).To(gomega.BeTrue())
+}
+
+func TestNameOutsidePatternDirectory(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer viper.Reset()
+
+	// without root, rendered paths are confined to the directory before the first template action
+	dir := t.TempDir()
+	viper.Set(env.SecretFileNamePattern, dir+"/app-{{.ObjectMeta.Labels.dir}}/secret.yaml")
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"dir": "foo"}}}
+	g.Expect(Name(secret)).To(gomega.Equal(filepath.Join(dir, "app-foo", "secret.yaml")))
+
+	secret.Labels["dir"] = "/../../../etc"
+	_, err := Name(secret)
+	g.Expect(IsConfinementError(err)).To(gomega.BeTrue())
+
+	viper.Set(env.SecretFileNamePattern, "{{.ObjectMeta.Labels.dir}}/secret.yaml")
+	secret.Labels["dir"] = "foo"
+	g.Expect(Name(secret)).To(gomega.Equal(filepath.Join("foo", "secret.yaml")))
+
+	secret.Labels["dir"] = "../etc"
+	_, err = Name(secret)
+	g.Expect(IsConfinementError(err)).To(gomega.BeTrue())
+}
+
+func TestWriteAllFilePerSecretKeysConfined(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	viper.Set(env.SecretFileSingle, true)
+	defer viper.Reset()
+
+	dir, outside := t.TempDir(), t.TempDir()
+	g.Expect(os.Symlink(filepath.Join(outside, "foo"), filepath.Join(dir, "link"))).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(outside, "foo"), []byte("foo"), 0644)).To(gomega.Succeed())
+
+	for _, key := range []string{"..", ".", "", "../foo", "link"} {
+		err := WriteAll(dir, map[interface{}]interface{}{key: "bar"})
+		g.Expect(IsConfinementError(err)).To(gomega.BeTrue(), key)
+	}
+
+	b, err := os.ReadFile(filepath.Join(outside, "foo"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(b)).To(gomega.Equal("foo"))
+}
+
+func TestWriteAllSymlinkSwapped(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer viper.Reset()
+
+	root, outside := t.TempDir(), t.TempDir()
+	viper.Set(env.SecretFileRoot, root)
+	g.Expect(os.WriteFile(filepath.Join(outside, "secret.yaml"), []byte("foo: bar\n"), 0644)).To(gomega.Succeed())
+
+	// a directory swapped for a symlink after the path has been confined
+	filename, err := Confine(filepath.Join(root, "link", "secret.yaml"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(os.Symlink(outside, filepath.Join(root, "link"))).To(gomega.Succeed())
+
+	g.Expect(WriteAll(filename, testData)).NotTo(gomega.Succeed())
+	g.Expect(WriteAtomic(filename, []byte(testString))).NotTo(gomega.Succeed())
+	_, err = ReadAll(filename)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	viper.Set(env.SecretFileSingle, true)
+	g.Expect(WriteAll(filepath.Join(root, "link"), testData)).NotTo(gomega.Succeed())
+	_, err = ReadAll(filepath.Join(root, "link"))
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	b, err := os.ReadFile(filepath.Join(outside, "secret.yaml"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(b)).To(gomega.Equal("foo: bar\n"))
+	files, err := os.ReadDir(outside)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(files).To(gomega.HaveLen(1))
+}
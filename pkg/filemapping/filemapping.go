@@ -0,0 +1,130 @@
+package filemapping
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/apis/v1alpha1"
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+
+	"github.com/spf13/viper"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// keys contains all configuration keys set by a mapping.
+var keys = []string{
+	env.SecretLabelSelector,
+	env.SecretAnnotationSelector,
+	env.SecretNameSelector,
+	env.SecretTypeSelector,
+	env.SecretCondition,
+	env.SecretContentSelector,
+	env.SecretFileNamePattern,
+	env.SecretFilePropertyPattern,
+	env.SecretFileSingle,
+	env.CallbackURL,
+	env.CallbackMethod,
+	env.CallbackBody,
+	env.CallbackContentType,
+}
+
+// defaults contains the configuration from environment variables and flags, before any mapping was applied.
+var defaults map[string]interface{}
+
+// Configured returns true, if the sidecar is configured by a mapping (see [env.MappingName]).
+func Configured() bool {
+	return viper.GetString(env.MappingName) != ""
+}
+
+// Name returns the name of the configured mapping. The namespace defaults to the namespace of the pod.
+func Name() (types.NamespacedName, error) {
+	namespace := viper.GetString(env.MappingNamespace)
+	if namespace == "" {
+		namespace = viper.GetString(env.PodNamespace)
+	}
+	if namespace == "" {
+		return types.NamespacedName{}, errors.New("mapping namespace is required, if the pod namespace is not set")
+	}
+	return types.NamespacedName{Namespace: namespace, Name: viper.GetString(env.MappingName)}, nil
+}
+
+// Get reads the configured mapping.
+func Get(ctx context.Context, c client.Reader) (*v1alpha1.SecretFileMapping, error) {
+	name, err := Name()
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := &v1alpha1.SecretFileMapping{}
+	if err := c.Get(ctx, name, mapping); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// Settings returns the configuration keys and values set by the given mapping. Keys not set by the mapping are missing.
+func Settings(spec *v1alpha1.SecretFileMappingSpec) map[string]interface{} {
+	settings := make(map[string]interface{})
+	set := func(key, value string) {
+		if value != "" {
+			settings[key] = value
+		}
+	}
+
+	set(env.SecretLabelSelector, spec.Selector.Label)
+	set(env.SecretAnnotationSelector, spec.Selector.Annotation)
+	set(env.SecretNameSelector, spec.Selector.Name)
+	set(env.SecretTypeSelector, strings.Join(spec.Selector.Types, ","))
+	set(env.SecretCondition, spec.Selector.Condition)
+	set(env.SecretContentSelector, spec.Content)
+	set(env.SecretFileNamePattern, spec.File.NamePattern)
+	set(env.SecretFilePropertyPattern, spec.File.PropertyPattern)
+	if spec.Format != "" {
+		settings[env.SecretFileSingle] = spec.Format == v1alpha1.FormatFiles
+	}
+	if spec.Callback != nil {
+		set(env.CallbackURL, spec.Callback.URL)
+		set(env.CallbackMethod, spec.Callback.Method)
+		set(env.CallbackBody, spec.Callback.Body)
+		set(env.CallbackContentType, spec.Callback.ContentType)
+	}
+	return settings
+}
+
+// Apply the given mapping to the configuration. Settings not set by the mapping fall back to the environment variables
+// and flags. Returns the previous configuration, which can be restored with [Restore]. Must not be called while secrets
+// are processed, as the configuration is not safe for concurrent use.
+func Apply(spec *v1alpha1.SecretFileMappingSpec) map[string]interface{} {
+	previous := snapshot()
+	if defaults == nil {
+		defaults = previous
+	}
+
+	settings := Settings(spec)
+	for _, key := range keys {
+		if value, ok := settings[key]; ok {
+			viper.Set(key, value)
+		} else {
+			viper.Set(key, defaults[key])
+		}
+	}
+	return previous
+}
+
+// Restore a configuration returned by [Apply].
+func Restore(previous map[string]interface{}) {
+	for key, value := range previous {
+		viper.Set(key, value)
+	}
+}
+
+func snapshot() map[string]interface{} {
+	values := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		values[key] = viper.Get(key)
+	}
+	return values
+}
@@ -0,0 +1,103 @@
+package filemapping
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/apis/v1alpha1"
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestName(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	g.Expect(Configured()).To(BeFalse())
+
+	viper.Set(env.MappingName, "foo")
+	g.Expect(Configured()).To(BeTrue())
+	_, err := Name()
+	g.Expect(err).To(MatchError("mapping namespace is required, if the pod namespace is not set"))
+
+	viper.Set(env.PodNamespace, "a")
+	g.Expect(Name()).To(Equal(types.NamespacedName{Namespace: "a", Name: "foo"}))
+
+	viper.Set(env.MappingNamespace, "b")
+	g.Expect(Name()).To(Equal(types.NamespacedName{Namespace: "b", Name: "foo"}))
+}
+
+func TestSettings(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(Settings(&v1alpha1.SecretFileMappingSpec{})).To(BeEmpty())
+
+	g.Expect(Settings(&v1alpha1.SecretFileMappingSpec{
+		Selector: v1alpha1.SecretSelector{Label: "foo=bar", Types: []string{"kubernetes.io/tls", "Opaque"}},
+		File:     v1alpha1.FileSpec{NamePattern: "/tmp/{{.ObjectMeta.Name}}"},
+		Format:   v1alpha1.FormatFiles,
+		Callback: &v1alpha1.Callback{URL: "http://localhost:8080/reload"},
+	})).To(Equal(map[string]interface{}{
+		env.SecretLabelSelector:   "foo=bar",
+		env.SecretTypeSelector:    "kubernetes.io/tls,Opaque",
+		env.SecretFileNamePattern: "/tmp/{{.ObjectMeta.Name}}",
+		env.SecretFileSingle:      true,
+		env.CallbackURL:           "http://localhost:8080/reload",
+	}))
+}
+
+func TestApply(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+	defer func() { defaults = nil }()
+
+	viper.Set(env.SecretLabelSelector, "env=default")
+	viper.Set(env.SecretFileNamePattern, "/tmp/default.yaml")
+
+	previous := Apply(&v1alpha1.SecretFileMappingSpec{Selector: v1alpha1.SecretSelector{Label: "env=first"}})
+	g.Expect(viper.GetString(env.SecretLabelSelector)).To(Equal("env=first"))
+	g.Expect(viper.GetString(env.SecretFileNamePattern)).To(Equal("/tmp/default.yaml"))
+	g.Expect(previous).To(HaveKeyWithValue(env.SecretLabelSelector, "env=default"))
+
+	// settings no longer set by the mapping fall back to the defaults
+	previous = Apply(&v1alpha1.SecretFileMappingSpec{File: v1alpha1.FileSpec{NamePattern: "/tmp/second.yaml"}})
+	g.Expect(viper.GetString(env.SecretLabelSelector)).To(Equal("env=default"))
+	g.Expect(viper.GetString(env.SecretFileNamePattern)).To(Equal("/tmp/second.yaml"))
+
+	Restore(previous)
+	g.Expect(viper.GetString(env.SecretLabelSelector)).To(Equal("env=first"))
+	g.Expect(viper.GetString(env.SecretFileNamePattern)).To(Equal("/tmp/default.yaml"))
+}
+
+func TestGet(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.MappingName, "foo")
+	viper.Set(env.MappingNamespace, "a")
+
+	c := fake.NewClientBuilder().WithScheme(testScheme(g)).WithObjects(testMapping(1)).Build()
+	mapping, err := Get(context.TODO(), c)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mapping.Spec.Selector.Label).To(Equal("foo=bar"))
+}
+
+func testScheme(g *WithT) *runtime.Scheme {
+	s := runtime.NewScheme()
+	g.Expect(v1alpha1.AddToScheme(s)).To(Succeed())
+	return s
+}
+
+func testMapping(generation int64) *v1alpha1.SecretFileMapping {
+	return &v1alpha1.SecretFileMapping{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "foo", Generation: generation},
+		Spec: v1alpha1.SecretFileMappingSpec{
+			Selector: v1alpha1.SecretSelector{Label: "foo=bar"},
+		},
+	}
+}
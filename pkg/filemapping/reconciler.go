@@ -0,0 +1,71 @@
+package filemapping
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/apis/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// StatusInterval is the interval the number of synced and failed secrets is refreshed in.
+const StatusInterval = time.Minute
+
+// Reconciler watches the configured mapping. Once its generation differs from the applied one, Changed is called to
+// re-apply the mapping. Otherwise, the number of synced and failed secrets is reported in the status of the mapping.
+type Reconciler struct {
+	client.Client
+
+	// Generation of the applied mapping.
+	Generation int64
+	// Changed is called, once the mapping changed, e.g. to restart the manager with the new mapping.
+	Changed func()
+	// Results of the secret controllers running with the applied mapping.
+	Results *Results
+}
+
+var _ reconcile.Reconciler = &Reconciler{}
+
+func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	mapping := &v1alpha1.SecretFileMapping{}
+	if err := r.Client.Get(ctx, request.NamespacedName, mapping); err != nil {
+		if errors.IsNotFound(err) {
+			// keep the applied configuration
+			slog.Warn("mapping not found, keeping the applied configuration", "mapping", request.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if mapping.Generation != r.Generation {
+		slog.Info("mapping changed, re-applying", "mapping", request.NamespacedName, "generation", mapping.Generation)
+		r.Changed()
+		return reconcile.Result{}, nil
+	}
+
+	synced, failed := r.Results.Counts()
+	if mapping.Status.SyncedSecrets != synced || mapping.Status.FailedSecrets != failed {
+		patch := client.MergeFrom(mapping.DeepCopy())
+		mapping.Status.SyncedSecrets = synced
+		mapping.Status.FailedSecrets = failed
+		if err := r.Client.Status().Patch(ctx, mapping, patch); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	return reconcile.Result{RequeueAfter: StatusInterval}, nil
+}
+
+// UpdateStatus reports the result of applying the given mapping.
+func UpdateStatus(ctx context.Context, c client.Client, mapping *v1alpha1.SecretFileMapping, applyErr error) error {
+	patch := client.MergeFrom(mapping.DeepCopy())
+	mapping.Status.ObservedGeneration = mapping.Generation
+	mapping.Status.LastError = ""
+	if applyErr != nil {
+		mapping.Status.LastError = applyErr.Error()
+	}
+	return c.Status().Patch(ctx, mapping, patch)
+}
@@ -0,0 +1,85 @@
+package filemapping
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var req = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "a", Name: "foo"}}
+
+func TestReconcile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretLabelSelector, "foo=bar")
+
+	s := testScheme(g)
+	g.Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+
+	mapping := testMapping(1)
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(
+		mapping,
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "s1", Labels: map[string]string{"foo": "bar"}}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "s2", Labels: map[string]string{"foo": "bar"}}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "s3"}},
+	).WithStatusSubresource(mapping).Build()
+
+	results := &Results{}
+	results.Record(types.NamespacedName{Namespace: "a", Name: "s1"}, nil)
+	results.Record(types.NamespacedName{Namespace: "a", Name: "s2"}, errors.New("invalid content"))
+	results.Record(types.NamespacedName{Namespace: "a", Name: "s3"}, nil)
+	results.Forget(types.NamespacedName{Namespace: "a", Name: "s3"})
+
+	changed := false
+	r := &Reconciler{Client: c, Generation: 1, Changed: func() { changed = true }, Results: results}
+
+	// the number of synced and failed secrets is reported
+	result, err := r.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(StatusInterval))
+	g.Expect(changed).To(BeFalse())
+
+	g.Expect(c.Get(context.TODO(), req.NamespacedName, mapping)).To(Succeed())
+	g.Expect(mapping.Status.SyncedSecrets).To(BeEquivalentTo(1))
+	g.Expect(mapping.Status.FailedSecrets).To(BeEquivalentTo(1))
+
+	// other generations are re-applied
+	r.Generation = 2
+	_, err = r.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(changed).To(BeTrue())
+
+	// deleted mappings keep the applied configuration
+	changed = false
+	g.Expect(c.Delete(context.TODO(), mapping)).To(Succeed())
+	_, err = r.Reconcile(context.TODO(), req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(changed).To(BeFalse())
+}
+
+func TestUpdateStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	mapping := testMapping(3)
+	c := fake.NewClientBuilder().WithScheme(testScheme(g)).WithObjects(mapping).WithStatusSubresource(mapping).Build()
+
+	g.Expect(UpdateStatus(context.TODO(), c, mapping, errors.New("invalid secret.file.name.pattern"))).To(Succeed())
+	g.Expect(c.Get(context.TODO(), req.NamespacedName, mapping)).To(Succeed())
+	g.Expect(mapping.Status.ObservedGeneration).To(BeEquivalentTo(3))
+	g.Expect(mapping.Status.LastError).To(Equal("invalid secret.file.name.pattern"))
+
+	g.Expect(UpdateStatus(context.TODO(), c, mapping, nil)).To(Succeed())
+	g.Expect(c.Get(context.TODO(), req.NamespacedName, mapping)).To(Succeed())
+	g.Expect(mapping.Status.LastError).To(BeEmpty())
+}
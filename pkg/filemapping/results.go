@@ -0,0 +1,72 @@
+package filemapping
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Results collects the result of syncing each secret with the applied mapping, so the status of the mapping reports
+// the secrets actually written. A nil *Results ignores all results.
+type Results struct {
+	mu sync.Mutex
+	// failed is true for secrets, which could not be synced, and false for synced ones.
+	failed map[types.NamespacedName]bool
+}
+
+// Record the result of syncing the secret.
+func (r *Results) Record(key types.NamespacedName, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failed == nil {
+		r.failed = make(map[types.NamespacedName]bool)
+	}
+	r.failed[key] = err != nil
+}
+
+// Forget the secret, once its content has been removed.
+func (r *Results) Forget(key types.NamespacedName) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.failed, key)
+}
+
+// Replace all results by the result of syncing the given secrets together, e.g. into a template file.
+func (r *Results) Replace(keys []types.NamespacedName, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failed = make(map[types.NamespacedName]bool, len(keys))
+	for _, key := range keys {
+		r.failed[key] = err != nil
+	}
+}
+
+// Counts returns the number of synced and failed secrets.
+func (r *Results) Counts() (synced int32, failed int32) {
+	if r == nil {
+		return 0, 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, f := range r.failed {
+		if f {
+			failed++
+		} else {
+			synced++
+		}
+	}
+	return synced, failed
+}
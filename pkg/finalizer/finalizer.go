@@ -0,0 +1,148 @@
+package finalizer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/logger"
+	"github.com/jaconi-io/secret-file-provider/pkg/selector"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Sweeper periodically removes finalizers of no longer existing sidecar pods. See [Sweep].
+type Sweeper struct {
+	// Client is used to list and patch secrets.
+	Client client.Client
+	// Reader is used to list pods. Use an uncached reader to avoid caching all pods.
+	Reader client.Reader
+	// Interval between two sweeps.
+	Interval time.Duration
+}
+
+var _ manager.Runnable = &Sweeper{}
+
+// Start sweeping until the given context is done.
+func (s *Sweeper) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := Sweep(ctx, s.Client, s.Reader); err != nil {
+				slog.Error("finalizer sweep failed", "error", err)
+			}
+		}
+	}
+}
+
+// Remove the finalizer of this sidecar from all matching secrets.
+func Remove(ctx context.Context, c client.Client) error {
+	secrets, err := selector.Secrets(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	RemoveFrom(ctx, c, secrets)
+	return nil
+}
+
+// RemoveFrom removes the finalizer of this sidecar from the given secrets. Failures are logged per secret.
+func RemoveFrom(ctx context.Context, c client.Client, secrets []corev1.Secret) {
+	for _, secret := range secrets {
+		if !controllerutil.ContainsFinalizer(&secret, env.GetFinalizer()) {
+			continue
+		}
+		if _, err := controllerutil.CreateOrPatch(ctx, c, &secret, func() error {
+			controllerutil.RemoveFinalizer(&secret, env.GetFinalizer())
+			return nil
+		}); err != nil {
+			logger.New(&secret).Error("removing finalizer failed", "error", err)
+			continue
+		}
+	}
+}
+
+// Sweep removes finalizers of sidecars from all matching secrets, if the pod the sidecar ran in no longer exists. This
+// happens, if a pod is not terminated gracefully (e.g. killed due to OOM or evicted), so the sidecar had no chance to
+// remove its finalizer. Pods are looked up in the namespaces configured by [env.FinalizerSweepNamespace], or in all
+// namespaces if unset.
+func Sweep(ctx context.Context, c client.Client, pods client.Reader) error {
+	// Secrets are listed before pods: a finalizer can only be added by a pod existing before the secrets are listed, so
+	// it is contained in the pod list. Sidecars starting in between add finalizers unknown to the listed secrets, which
+	// are kept by the optimistic lock below.
+	secrets, err := selector.Secrets(ctx, c)
+	if err != nil {
+		return fmt.Errorf("listing secrets failed: %w", err)
+	}
+
+	alive, err := aliveFinalizers(ctx, pods)
+	if err != nil {
+		return fmt.Errorf("listing pods failed: %w", err)
+	}
+
+	for _, secret := range secrets {
+		var orphaned []string
+		for _, f := range secret.Finalizers {
+			if strings.HasPrefix(f, env.FinalizerPrefix) && !alive.Has(f) {
+				orphaned = append(orphaned, f)
+			}
+		}
+
+		if len(orphaned) == 0 {
+			continue
+		}
+
+		// Use an optimistic lock, as other sidecars might add or remove their finalizers concurrently.
+		patch := client.MergeFromWithOptions(secret.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		for _, f := range orphaned {
+			controllerutil.RemoveFinalizer(&secret, f)
+		}
+
+		if err := c.Patch(ctx, &secret, patch); err != nil {
+			logger.New(&secret).Error("removing orphaned finalizers failed", "finalizers", orphaned, "error", err)
+			continue
+		}
+
+		logger.New(&secret).Info("removed orphaned finalizers", "finalizers", orphaned)
+	}
+
+	return nil
+}
+
+// aliveFinalizers returns the finalizers of all sidecars in currently existing pods.
+func aliveFinalizers(ctx context.Context, c client.Reader) (sets.Set[string], error) {
+	namespaces := env.GetFinalizerSweepNamespaces()
+	if len(namespaces) == 0 {
+		// all namespaces
+		namespaces = []string{""}
+	}
+
+	result := sets.New[string]()
+	for _, ns := range namespaces {
+		// Only metadata is required, so there is no need to transfer complete pod specs.
+		pods := &metav1.PartialObjectMetadataList{}
+		pods.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PodList"))
+		if err := c.List(ctx, pods, client.InNamespace(ns)); err != nil {
+			return nil, err
+		}
+
+		for _, pod := range pods.Items {
+			result.Insert(env.GetFinalizerFor(pod.Name))
+		}
+	}
+
+	return result, nil
+}
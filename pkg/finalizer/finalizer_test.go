@@ -0,0 +1,151 @@
+package finalizer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRemove(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.PodName, "pod1")
+	viper.Set(env.SecretNameSelector, ".*")
+
+	secret := testSecret("a", "foo", nil, env.FinalizerPrefix+"pod1", env.FinalizerPrefix+"pod2")
+	c := fake.NewClientBuilder().WithObjects(secret).Build()
+
+	err := Remove(context.Background(), c)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	err = c.Get(context.Background(), client.ObjectKeyFromObject(secret), secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secret.Finalizers).To(ConsistOf(env.FinalizerPrefix + "pod2"))
+}
+
+func TestSweep(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretNameSelector, ".*")
+
+	longPodName := "pod-with-a-really-long-name-of-more-than-64-characters-which-is-more-than-what-k8s-allows"
+	secret1 := testSecret("a", "foo", nil, env.FinalizerPrefix+"alive", env.FinalizerPrefix+"dead", "example.com/other")
+	secret2 := testSecret("b", "bar", nil, env.GetFinalizerFor(longPodName), env.FinalizerPrefix+"dead")
+	c := fake.NewClientBuilder().WithObjects(
+		secret1,
+		secret2,
+		testPod("a", "alive"),
+		testPod("c", longPodName),
+	).Build()
+
+	err := Sweep(context.Background(), c, c)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	err = c.Get(context.Background(), client.ObjectKeyFromObject(secret1), secret1)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secret1.Finalizers).To(ConsistOf(env.FinalizerPrefix+"alive", "example.com/other"))
+
+	err = c.Get(context.Background(), client.ObjectKeyFromObject(secret2), secret2)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secret2.Finalizers).To(ConsistOf(env.GetFinalizerFor(longPodName)))
+
+	// pods in other namespaces are not considered alive, if the sweep is restricted
+	viper.Set(env.FinalizerSweepNamespace, "a")
+	err = Sweep(context.Background(), c, c)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	err = c.Get(context.Background(), client.ObjectKeyFromObject(secret2), secret2)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secret2.Finalizers).To(BeEmpty())
+}
+
+func TestSweepNamespaces(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretNameSelector, ".*")
+	viper.Set(env.FinalizerSweepNamespace, "a, ,")
+
+	// an empty element does not widen the sweep to all namespaces
+	secret := testSecret("b", "bar", nil, env.FinalizerPrefix+"other")
+	c := fake.NewClientBuilder().WithObjects(secret, testPod("c", "other")).Build()
+
+	err := Sweep(context.Background(), c, c)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	err = c.Get(context.Background(), client.ObjectKeyFromObject(secret), secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secret.Finalizers).To(BeEmpty())
+}
+
+func TestSweepStartingSidecar(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretNameSelector, ".*")
+
+	secret := testSecret("a", "foo", nil, env.FinalizerPrefix+"dead")
+	c := fake.NewClientBuilder().WithObjects(secret).Build()
+
+	// a sidecar starts right after the pods have been listed
+	pods := &startingSidecar{Reader: c, start: func(ctx context.Context) {
+		g.Expect(c.Create(ctx, testPod("a", "new"))).To(Succeed())
+		s := &corev1.Secret{}
+		g.Expect(c.Get(ctx, client.ObjectKeyFromObject(secret), s)).To(Succeed())
+		s.Finalizers = append(s.Finalizers, env.FinalizerPrefix+"new")
+		g.Expect(c.Update(ctx, s)).To(Succeed())
+	}}
+
+	err := Sweep(context.Background(), c, pods)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	err = c.Get(context.Background(), client.ObjectKeyFromObject(secret), secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secret.Finalizers).To(ContainElement(env.FinalizerPrefix + "new"))
+}
+
+// startingSidecar calls start once, after listing the pods.
+type startingSidecar struct {
+	client.Reader
+	start func(context.Context)
+}
+
+func (s *startingSidecar) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	err := s.Reader.List(ctx, list, opts...)
+	if s.start != nil {
+		s.start(ctx)
+		s.start = nil
+	}
+	return err
+}
+
+func testSecret(namespace, name string, labels map[string]string, finalizers ...string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  namespace,
+			Name:       name,
+			Labels:     labels,
+			Finalizers: finalizers,
+		},
+	}
+}
+
+func testPod(namespace, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			UID:       types.UID(name),
+		},
+	}
+}
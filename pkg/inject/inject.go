@@ -0,0 +1,151 @@
+package inject
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// name of the injected sidecar, init container and shared volume
+	name = "secret-file-provider"
+	// name of the injected init container, processing all secrets once before the application starts
+	initName = name + "-init"
+)
+
+// Injector is a mutating admission webhook, injecting the sidecar into pods annotated with [env.InjectAnnotation]. Use
+// [NewInjector] to create it, as requests are handled concurrently.
+type Injector struct {
+	image   string
+	decoder admission.Decoder
+}
+
+var _ admission.Handler = &Injector{}
+
+// NewInjector creates an injector for the given image of the sidecar.
+func NewInjector(image string) *Injector {
+	return &Injector{image: image, decoder: admission.NewDecoder(scheme.Scheme)}
+}
+
+// Handle an admission request for a pod.
+func (i *Injector) Handle(_ context.Context, req admission.Request) admission.Response {
+	pod := &corev1.Pod{}
+	if err := i.decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if pod.Annotations[env.InjectAnnotation] != "true" {
+		return admission.Allowed("injection not requested")
+	}
+	if injected(pod) {
+		return admission.Allowed("already injected")
+	}
+
+	if err := Inject(pod, i.image); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	raw, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, raw)
+}
+
+// injected returns true, if the sidecar is already part of the given pod.
+func injected(pod *corev1.Pod) bool {
+	return slices.ContainsFunc(pod.Spec.Containers, func(c corev1.Container) bool {
+		return c.Name == name
+	})
+}
+
+// Inject adds the sidecar, an init container in one-shot mode (see [env.Oneshot]) and a shared volume to the given pod.
+// The sidecar is configured by the configuration annotations of the pod (see [env.ConfigAnnotationPrefix]), which are
+// passed as environment variables. The shared volume is mounted into all containers at the path given by
+// [env.MountPathAnnotation]. All files are confined to the shared volume (see [env.SecretFileRoot]), as the application
+// would not see them otherwise. Returns an error, if the configuration annotations are invalid.
+func Inject(pod *corev1.Pod, image string) error {
+	config, err := env.ConfigFromAnnotations(pod.Annotations)
+	if err != nil {
+		return err
+	}
+
+	mountPath := pod.Annotations[env.MountPathAnnotation]
+	if mountPath == "" {
+		mountPath = env.DefaultMountPath
+	}
+
+	if root, ok := config[env.SecretFileRoot]; !ok {
+		config[env.SecretFileRoot] = mountPath
+	} else if !within(mountPath, root) {
+		return fmt.Errorf("%s %q is outside of the mount path %q", env.SecretFileRoot, root, mountPath)
+	}
+	// templated and relative patterns are confined by the sidecar at runtime
+	if pattern := config[env.SecretFileNamePattern]; path.IsAbs(pattern) && !strings.Contains(pattern, "{{") &&
+		!within(config[env.SecretFileRoot], pattern) {
+		return fmt.Errorf("%s %q is outside of %q", env.SecretFileNamePattern, pattern, config[env.SecretFileRoot])
+	}
+
+	mount := corev1.VolumeMount{Name: name, MountPath: mountPath}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name:         name,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+
+	// the application reads the files from the shared volume
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].VolumeMounts = append(pod.Spec.Containers[i].VolumeMounts, mount)
+	}
+
+	environment := envVars(config)
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{
+		Name:         initName,
+		Image:        image,
+		Env:          append(slices.Clone(environment), corev1.EnvVar{Name: env.EnvName(env.Oneshot), Value: "true"}),
+		VolumeMounts: []corev1.VolumeMount{mount},
+	})
+	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+		Name:         name,
+		Image:        image,
+		Env:          environment,
+		VolumeMounts: []corev1.VolumeMount{mount},
+	})
+
+	return nil
+}
+
+// within returns true, if the given path is the directory or any path below it.
+func within(dir, p string) bool {
+	dir, p = path.Clean(dir), path.Clean(p)
+	return p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// envVars returns the environment variables for the given configuration, plus the pod name (see [env.PodName]) taken
+// from the pod via the downward API. Variables are sorted by name, so repeated injections are stable.
+func envVars(config map[string]string) []corev1.EnvVar {
+	vars := []corev1.EnvVar{{
+		Name: env.EnvName(env.PodName),
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+		},
+	}}
+
+	for key, value := range config {
+		vars = append(vars, corev1.EnvVar{Name: env.EnvName(key), Value: value})
+	}
+	slices.SortFunc(vars[1:], func(a, b corev1.EnvVar) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return vars
+}
@@ -0,0 +1,150 @@
+package inject
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestInject(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	viper.SetDefault(env.SecretLabelSelector, "")
+	viper.SetDefault(env.SecretFileNamePattern, "")
+	viper.SetDefault(env.SecretFileRoot, "")
+
+	pod := testPod(map[string]string{
+		env.InjectAnnotation:                                   "true",
+		env.MountPathAnnotation:                                "/etc/secrets",
+		env.ConfigAnnotationPrefix + env.SecretLabelSelector:   "company=acme",
+		env.ConfigAnnotationPrefix + env.SecretFileNamePattern: "/etc/secrets/acme.yaml",
+	})
+
+	g.Expect(Inject(pod, "sidecar:latest")).To(Succeed())
+
+	mount := corev1.VolumeMount{Name: name, MountPath: "/etc/secrets"}
+	g.Expect(pod.Spec.Volumes).To(ConsistOf(corev1.Volume{Name: name, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}))
+
+	g.Expect(pod.Spec.Containers).To(HaveLen(2))
+	g.Expect(pod.Spec.Containers[0].VolumeMounts).To(ConsistOf(mount))
+
+	sidecar := pod.Spec.Containers[1]
+	g.Expect(sidecar.Name).To(Equal(name))
+	g.Expect(sidecar.Image).To(Equal("sidecar:latest"))
+	g.Expect(sidecar.VolumeMounts).To(ConsistOf(mount))
+	g.Expect(sidecar.Env).To(Equal([]corev1.EnvVar{
+		{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+		{Name: "SECRET_FILE_NAME_PATTERN", Value: "/etc/secrets/acme.yaml"},
+		{Name: "SECRET_FILE_ROOT", Value: "/etc/secrets"},
+		{Name: "SECRET_SELECTOR_LABEL", Value: "company=acme"},
+	}))
+
+	g.Expect(pod.Spec.InitContainers).To(HaveLen(1))
+	init := pod.Spec.InitContainers[0]
+	g.Expect(init.Name).To(Equal(initName))
+	g.Expect(init.VolumeMounts).To(ConsistOf(mount))
+	g.Expect(init.Env).To(HaveLen(5))
+	g.Expect(init.Env).To(ContainElement(corev1.EnvVar{Name: "ONESHOT", Value: "true"}))
+}
+
+func TestInjectDefaultMountPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	pod := testPod(map[string]string{env.InjectAnnotation: "true"})
+	g.Expect(Inject(pod, "sidecar:latest")).To(Succeed())
+	g.Expect(pod.Spec.Containers[0].VolumeMounts).To(ConsistOf(corev1.VolumeMount{Name: name, MountPath: env.DefaultMountPath}))
+}
+
+func TestInjectOutsideMountPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer viper.Reset()
+	viper.SetDefault(env.SecretFileNamePattern, "")
+	viper.SetDefault(env.SecretFileRoot, "")
+
+	// files the application cannot see are denied
+	pod := testPod(map[string]string{
+		env.MountPathAnnotation:                                "/etc/secrets",
+		env.ConfigAnnotationPrefix + env.SecretFileNamePattern: "/etc/secrets-other/acme.yaml",
+	})
+	g.Expect(Inject(pod, "sidecar:latest")).To(MatchError(ContainSubstring("is outside of \"/etc/secrets\"")))
+
+	pod = testPod(map[string]string{
+		env.MountPathAnnotation:                         "/etc/secrets",
+		env.ConfigAnnotationPrefix + env.SecretFileRoot: "/var",
+	})
+	g.Expect(Inject(pod, "sidecar:latest")).To(MatchError(ContainSubstring("is outside of the mount path")))
+
+	// templated patterns are confined at runtime, narrower roots are kept
+	pod = testPod(map[string]string{
+		env.MountPathAnnotation:                                "/etc/secrets",
+		env.ConfigAnnotationPrefix + env.SecretFileRoot:        "/etc/secrets/acme",
+		env.ConfigAnnotationPrefix + env.SecretFileNamePattern: "/etc/secrets/{{.Labels.company}}.yaml",
+	})
+	g.Expect(Inject(pod, "sidecar:latest")).To(Succeed())
+	g.Expect(pod.Spec.Containers[1].Env).To(ContainElement(corev1.EnvVar{Name: "SECRET_FILE_ROOT", Value: "/etc/secrets/acme"}))
+}
+
+func TestInjectInvalidConfiguration(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	pod := testPod(map[string]string{env.ConfigAnnotationPrefix + "secret.selector.lable": "company=acme"})
+	g.Expect(Inject(pod, "sidecar:latest")).To(MatchError(ContainSubstring("unknown or unsupported key")))
+}
+
+func TestHandle(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	injector := NewInjector("sidecar:latest")
+
+	// pods without annotation are not changed
+	response := injector.Handle(context.TODO(), request(g, testPod(nil)))
+	g.Expect(response.Allowed).To(BeTrue())
+	g.Expect(response.Patches).To(BeEmpty())
+
+	pod := testPod(map[string]string{env.InjectAnnotation: "true"})
+	response = injector.Handle(context.TODO(), request(g, pod))
+	g.Expect(response.Allowed).To(BeTrue())
+	g.Expect(response.Patches).NotTo(BeEmpty())
+
+	// injection is idempotent
+	g.Expect(Inject(pod, "sidecar:latest")).To(Succeed())
+	response = injector.Handle(context.TODO(), request(g, pod))
+	g.Expect(response.Allowed).To(BeTrue())
+	g.Expect(response.Patches).To(BeEmpty())
+
+	// invalid configuration is denied
+	pod = testPod(map[string]string{env.InjectAnnotation: "true", env.ConfigAnnotationPrefix + "unknown": "foo"})
+	response = injector.Handle(context.TODO(), request(g, pod))
+	g.Expect(response.Allowed).To(BeFalse())
+}
+
+func testPod(annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "app", Annotations: annotations},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "app:latest"}},
+		},
+	}
+}
+
+func request(g *WithT, pod *corev1.Pod) admission.Request {
+	raw, err := json.Marshal(pod)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
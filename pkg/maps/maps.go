@@ -2,53 +2,137 @@ package maps
 
 import (
 	gomaps "maps"
+	"reflect"
+	"slices"
 )
 
+// Indexed contains list elements by their index. [Union] merges each element into the element at the same index of the
+// original list; indices beyond its end append the element, so an index rendered from a label cannot grow the list
+// arbitrarily. [Drop] clears the elements at the given indices. Cleared elements are set to nil instead of being
+// removed, so the indices of all other elements stay the same.
+type Indexed map[int]interface{}
+
+// Append contains list elements, which [Union] appends to the original list, unless they are already contained. [Drop]
+// removes one equal element for each of them.
+type Append []interface{}
+
+// Element is a single list element (a map), identified by the value of its property Key. [Union] replaces the element
+// with the same identity in the original list, or appends it if there is none. [Drop] removes the element with the same
+// identity.
+type Element struct {
+	Key   interface{}
+	Value map[interface{}]interface{}
+}
+
+// identifies returns true, if the given list element has the same identity as the element e.
+func (e Element) identifies(element interface{}) bool {
+	m, ok := element.(map[interface{}]interface{})
+	return ok && reflect.DeepEqual(m[e.Key], e.Value[e.Key])
+}
+
 // Union will merge two given maps recursive, where the values of the 'right' one will overwrite the ones
-// from the 'left'.
+// from the 'left'. Lists are merged element-wise, if the right one is [Indexed], [Append] or an [Element]; other lists
+// are overwritten.
 func Union(left, right map[interface{}]interface{}) map[interface{}]interface{} {
+	return union(left, right, false)
+}
+
+// Merge combines two maps recursive like [Union], but keeps [Indexed], [Append] and [Element] lists, so the result can
+// still be passed to [Union] and [Drop] to add or remove exactly the addressed list elements.
+func Merge(left, right map[interface{}]interface{}) map[interface{}]interface{} {
+	return union(left, right, true)
+}
+
+func union(left, right map[interface{}]interface{}, keepLists bool) map[interface{}]interface{} {
 	out := gomaps.Clone(left)
+	if out == nil {
+		out = make(map[interface{}]interface{})
+	}
 	for k, v := range right {
-		// If you use map[string]interface{}, ok is always false here, because [yaml.Unmarshal] returns
-		// map[interface{}]interface{}.
-		if v, ok := v.(map[interface{}]interface{}); ok {
-			if bv, ok := out[k]; ok {
-				if bv, ok := bv.(map[interface{}]interface{}); ok {
-					out[k] = Union(bv, v)
-					continue
-				}
+		out[k] = merge(out[k], v, keepLists)
+	}
+	return out
+}
+
+// merge a single right value into the left one.
+func merge(left, right interface{}, keepLists bool) interface{} {
+	// If you use map[string]interface{}, ok is always false here, because [yaml.Unmarshal] returns
+	// map[interface{}]interface{}.
+	switch r := right.(type) {
+	case map[interface{}]interface{}:
+		l, _ := left.(map[interface{}]interface{})
+		return union(l, r, keepLists)
+	case Indexed:
+		if keepLists {
+			l, _ := left.(Indexed)
+			out := gomaps.Clone(l)
+			if out == nil {
+				out = make(Indexed)
+			}
+			for i, element := range r {
+				out[i] = merge(out[i], element, true)
 			}
+			return out
+		}
+
+		l, _ := left.([]interface{})
+		out := slices.Clone(l)
+		for _, i := range slices.Sorted(gomaps.Keys(r)) {
+			if i >= len(out) {
+				out = append(out, merge(nil, r[i], false))
+				continue
+			}
+			out[i] = merge(out[i], r[i], false)
+		}
+		return out
+	case Append:
+		if keepLists {
+			l, _ := left.(Append)
+			return append(slices.Clone(l), r...)
+		}
+
+		l, _ := left.([]interface{})
+		out := slices.Clone(l)
+		for _, element := range r {
+			element = merge(nil, element, false)
+			if !contains(out, element) {
+				out = append(out, element)
+			}
+		}
+		return out
+	case Element:
+		element := union(nil, r.Value, keepLists)
+		if keepLists {
+			return Element{Key: r.Key, Value: element}
+		}
+
+		l, _ := left.([]interface{})
+		out := slices.Clone(l)
+		if i := slices.IndexFunc(out, r.identifies); i >= 0 {
+			out[i] = element
+		} else {
+			out = append(out, element)
 		}
-		out[k] = v
+		return out
+	default:
+		return right
 	}
-	return out
 }
 
-// Drop will remove known entries from a given map recursively.
+// Drop will remove known entries from a given map recursively. List elements are removed, if given as [Indexed],
+// [Append] or an [Element].
 func Drop(origin, toRemove map[interface{}]interface{}) map[interface{}]interface{} {
 	out := gomaps.Clone(origin)
 	for k, v := range toRemove {
-		if _, ok := out[k]; !ok {
+		o, ok := out[k]
+		if !ok {
 			// not existing, don't need to care
 			continue
 		}
-		if v, ok := v.(map[interface{}]interface{}); ok {
-			// found v itself a map
-			if outChild, ok := out[k].(map[interface{}]interface{}); ok {
-				// it's a map
-				child := Drop(outChild, v)
-				if child == nil {
-					delete(out, k)
-				} else {
-					out[k] = child
-				}
-			} else {
-				// simple value in original map: just drop it
-				delete(out, k)
-			}
-		} else {
-			// found leaf, drop entry
+		if rest := drop(o, v); rest == nil {
 			delete(out, k)
+		} else {
+			out[k] = rest
 		}
 	}
 	if len(out) < 1 {
@@ -56,3 +140,74 @@ func Drop(origin, toRemove map[interface{}]interface{}) map[interface{}]interfac
 	}
 	return out
 }
+
+// drop removes a single value from the origin one. Returns what is left of the origin, or nil if nothing is left.
+func drop(origin, toRemove interface{}) interface{} {
+	switch r := toRemove.(type) {
+	case map[interface{}]interface{}:
+		if o, ok := origin.(map[interface{}]interface{}); ok {
+			if rest := Drop(o, r); rest != nil {
+				return rest
+			}
+		}
+		// simple value in original map: just drop it
+		return nil
+	case Indexed:
+		o, ok := origin.([]interface{})
+		if !ok {
+			return nil
+		}
+		out := slices.Clone(o)
+		for i, element := range r {
+			if i < len(out) {
+				// removing the element would shift all following ones, which might belong to other secrets
+				out[i] = drop(out[i], element)
+			}
+		}
+		return listOrNil(trimNil(out))
+	case Append:
+		o, ok := origin.([]interface{})
+		if !ok {
+			return nil
+		}
+		out := slices.Clone(o)
+		for _, element := range r {
+			element = merge(nil, element, false)
+			if i := slices.IndexFunc(out, func(e interface{}) bool { return reflect.DeepEqual(e, element) }); i >= 0 {
+				out = slices.Delete(out, i, i+1)
+			}
+		}
+		return listOrNil(out)
+	case Element:
+		o, ok := origin.([]interface{})
+		if !ok {
+			return nil
+		}
+		return listOrNil(slices.DeleteFunc(slices.Clone(o), r.identifies))
+	default:
+		// found leaf, drop entry
+		return nil
+	}
+}
+
+func contains(list []interface{}, element interface{}) bool {
+	return slices.ContainsFunc(list, func(e interface{}) bool {
+		return reflect.DeepEqual(e, element)
+	})
+}
+
+// trimNil removes trailing nil elements, which does not change the indices of the remaining ones.
+func trimNil(list []interface{}) []interface{} {
+	for len(list) > 0 && list[len(list)-1] == nil {
+		list = list[:len(list)-1]
+	}
+	return list
+}
+
+// listOrNil returns nil for empty lists, so the list is removed from its parent.
+func listOrNil(list []interface{}) interface{} {
+	if len(list) < 1 {
+		return nil
+	}
+	return list
+}
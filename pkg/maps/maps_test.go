@@ -114,3 +114,165 @@ func TestUnion(t *testing.T) {
 
 	g.Expect(result).To(Equal(expectedResult))
 }
+
+func TestUnion_lists(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	left := map[interface{}]interface{}{
+		"appended": []interface{}{"a"},
+		"indexed":  []interface{}{map[interface{}]interface{}{"id": "a", "name": "foo"}},
+		"replaced": []interface{}{"a", "b"},
+	}
+	right := map[interface{}]interface{}{
+		// appending an already contained element keeps the list unchanged
+		"appended": Append{"b", "a"},
+		"indexed": Indexed{
+			0: map[interface{}]interface{}{"id": "b"},
+			2: "c",
+		},
+		"replaced": []interface{}{"c"},
+		"new":      Append{map[interface{}]interface{}{"nested": Indexed{0: "d"}}},
+	}
+	expectedResult := map[interface{}]interface{}{
+		"appended": []interface{}{"a", "b"},
+		// indices beyond the end of the list append the element
+		"indexed":  []interface{}{map[interface{}]interface{}{"id": "b", "name": "foo"}, "c"},
+		"replaced": []interface{}{"c"},
+		"new":      []interface{}{map[interface{}]interface{}{"nested": []interface{}{"d"}}},
+	}
+
+	g.Expect(Union(left, right)).To(Equal(expectedResult))
+
+	g.Expect(Union(nil, map[interface{}]interface{}{"huge": Indexed{1 << 40: "a"}})).To(Equal(map[interface{}]interface{}{
+		"huge": []interface{}{"a"},
+	}))
+}
+
+func TestDrop_lists(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	origin := map[interface{}]interface{}{
+		"appended": []interface{}{"a", "b", map[interface{}]interface{}{"id": "c"}},
+		"indexed": []interface{}{
+			map[interface{}]interface{}{"id": "a", "name": "foo"},
+			"b",
+			"c",
+		},
+		"single":   []interface{}{"a"},
+		"replaced": []interface{}{"a", "b"},
+	}
+	toDrop := map[interface{}]interface{}{
+		"appended": Append{"a", map[interface{}]interface{}{"id": "c"}},
+		"indexed": Indexed{
+			0: map[interface{}]interface{}{"id": "a"},
+			1: "b",
+			5: "f",
+		},
+		"single":   Append{"a"},
+		"replaced": []interface{}{"a"},
+	}
+	expectedResult := map[interface{}]interface{}{
+		"appended": []interface{}{"b"},
+		"indexed":  []interface{}{map[interface{}]interface{}{"name": "foo"}, nil, "c"},
+	}
+
+	g.Expect(Drop(origin, toDrop)).To(Equal(expectedResult))
+
+	// trailing elements are removed, the list is removed once it is empty
+	g.Expect(Drop(expectedResult, map[interface{}]interface{}{
+		"indexed": Indexed{2: "c"},
+	})).To(HaveKeyWithValue("indexed", []interface{}{map[interface{}]interface{}{"name": "foo"}}))
+	g.Expect(Drop(expectedResult, map[interface{}]interface{}{
+		"indexed": Indexed{0: "a", 2: "c"},
+	})).NotTo(HaveKey("indexed"))
+}
+
+func TestDrop_appendedReplaced(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	old := map[interface{}]interface{}{"clients": Append{"old"}}
+	updated := map[interface{}]interface{}{"clients": Append{"new"}}
+
+	// a changed value replaces the previous one, once the previous value is dropped
+	result := Union(Drop(Union(nil, old), old), updated)
+	g.Expect(result).To(Equal(map[interface{}]interface{}{"clients": []interface{}{"new"}}))
+
+	// only one of several equal elements is removed
+	result = Drop(map[interface{}]interface{}{"clients": []interface{}{"new", "other", "new"}}, updated)
+	g.Expect(result).To(Equal(map[interface{}]interface{}{"clients": []interface{}{"other", "new"}}))
+
+	g.Expect(Drop(result, map[interface{}]interface{}{"clients": Append{"new", "other"}})).To(BeNil())
+}
+
+func TestMerge(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	left := map[interface{}]interface{}{
+		"appended": Append{"a"},
+		"indexed":  Indexed{0: map[interface{}]interface{}{"id": "a"}},
+	}
+	right := map[interface{}]interface{}{
+		"appended": Append{"b"},
+		"indexed":  Indexed{0: map[interface{}]interface{}{"name": "foo"}, 1: "b"},
+	}
+	expectedResult := map[interface{}]interface{}{
+		"appended": Append{"a", "b"},
+		"indexed":  Indexed{0: map[interface{}]interface{}{"id": "a", "name": "foo"}, 1: "b"},
+	}
+
+	g.Expect(Merge(left, right)).To(Equal(expectedResult))
+}
+
+func TestUnion_element(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	left := map[interface{}]interface{}{
+		"clients": []interface{}{
+			map[interface{}]interface{}{"name": "acme", "id": "1", "secret": "old"},
+			map[interface{}]interface{}{"name": "company", "id": "2"},
+		},
+	}
+
+	// the element with the same identity is replaced
+	result := Union(left, map[interface{}]interface{}{
+		"clients": Element{Key: "name", Value: map[interface{}]interface{}{"name": "acme", "id": "3"}},
+	})
+	g.Expect(result).To(Equal(map[interface{}]interface{}{
+		"clients": []interface{}{
+			map[interface{}]interface{}{"name": "acme", "id": "3"},
+			map[interface{}]interface{}{"name": "company", "id": "2"},
+		},
+	}))
+
+	// new elements are appended
+	result = Union(result, map[interface{}]interface{}{
+		"clients": Element{Key: "name", Value: map[interface{}]interface{}{"name": "uni", "id": "4"}},
+	})
+	g.Expect(result["clients"]).To(HaveLen(3))
+	g.Expect(result["clients"].([]interface{})[2]).To(Equal(map[interface{}]interface{}{"name": "uni", "id": "4"}))
+}
+
+func TestDrop_element(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	origin := map[interface{}]interface{}{
+		"clients": []interface{}{
+			map[interface{}]interface{}{"name": "acme", "id": "1"},
+			map[interface{}]interface{}{"name": "company", "id": "2"},
+		},
+		"single": []interface{}{
+			map[interface{}]interface{}{"name": "acme"},
+		},
+	}
+	toDrop := map[interface{}]interface{}{
+		// the content of the element is irrelevant, only its identity
+		"clients": Element{Key: "name", Value: map[interface{}]interface{}{"name": "acme"}},
+		"single":  Element{Key: "name", Value: map[interface{}]interface{}{"name": "acme"}},
+	}
+
+	g.Expect(Drop(origin, toDrop)).To(Equal(map[interface{}]interface{}{
+		"clients": []interface{}{
+			map[interface{}]interface{}{"name": "company", "id": "2"},
+		},
+	}))
+}
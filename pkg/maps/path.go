@@ -0,0 +1,164 @@
+package maps
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Segment of a property path, either a map key or a list element.
+type Segment struct {
+	// Key of a map entry, if List is false.
+	Key string
+	// List is true, if the segment addresses a list element.
+	List bool
+	// Index of a list element, if List is true. A negative index appends the element to the list.
+	Index int
+}
+
+// ParsePath parses a property path into its segments. Segments are separated by dots. Segments containing dots can be
+// quoted ('clients."acme.com".id') or escaped ('clients.acme\.com.id'). List elements can be addressed by their index
+// ('clients[2].id') or appended ('clients[].id').
+func ParsePath(path string) ([]Segment, error) {
+	p := &pathParser{path: path}
+
+	var segments []Segment
+	for {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, Segment{Key: key})
+
+		for p.peek() == '[' {
+			index, err := p.index()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, Segment{List: true, Index: index})
+		}
+
+		if p.done() {
+			return segments, nil
+		}
+		if p.next() != '.' {
+			return nil, p.errorf("expecting '.'")
+		}
+	}
+}
+
+// Nest creates nested maps and lists for each of the given path segments, with the given value as leaf. Lists are
+// created as [Indexed] or [Append], so [Union] and [Drop] merge and remove exactly the addressed elements. The first
+// segment has to be a map key.
+func Nest(segments []Segment, value interface{}) map[interface{}]interface{} {
+	for i := len(segments) - 1; i > 0; i-- {
+		value = nestSegment(segments[i], value)
+	}
+	return map[interface{}]interface{}{segments[0].Key: value}
+}
+
+func nestSegment(segment Segment, value interface{}) interface{} {
+	switch {
+	case !segment.List:
+		return map[interface{}]interface{}{segment.Key: value}
+	case segment.Index < 0:
+		return Append{value}
+	default:
+		return Indexed{segment.Index: value}
+	}
+}
+
+type pathParser struct {
+	path string
+	pos  int
+}
+
+func (p *pathParser) done() bool {
+	return p.pos >= len(p.path)
+}
+
+func (p *pathParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.path[p.pos]
+}
+
+func (p *pathParser) next() byte {
+	c := p.peek()
+	p.pos++
+	return c
+}
+
+func (p *pathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid property path %q at position %d: %s", p.path, p.pos, fmt.Sprintf(format, args...))
+}
+
+// key parses a quoted or plain map key.
+func (p *pathParser) key() (string, error) {
+	var key strings.Builder
+
+	if p.peek() == '"' {
+		p.next()
+		for {
+			if p.done() {
+				return "", p.errorf("missing closing quote")
+			}
+
+			c := p.next()
+			switch c {
+			case '"':
+				return key.String(), nil
+			case '\\':
+				if p.done() {
+					return "", p.errorf("incomplete escape sequence")
+				}
+				key.WriteByte(p.next())
+			default:
+				key.WriteByte(c)
+			}
+		}
+	}
+
+	for !p.done() && p.peek() != '.' && p.peek() != '[' {
+		c := p.next()
+		if c == '\\' {
+			if p.done() {
+				return "", p.errorf("incomplete escape sequence")
+			}
+			c = p.next()
+		}
+		key.WriteByte(c)
+	}
+
+	if key.Len() == 0 {
+		return "", p.errorf("empty segment")
+	}
+	return key.String(), nil
+}
+
+// index parses a list index in brackets. Empty brackets result in a negative index.
+func (p *pathParser) index() (int, error) {
+	p.next()
+
+	start := p.pos
+	for !p.done() && p.peek() != ']' {
+		p.next()
+	}
+	if p.done() {
+		return 0, p.errorf("missing closing bracket")
+	}
+
+	digits := p.path[start:p.pos]
+	p.next()
+
+	if digits == "" {
+		return -1, nil
+	}
+
+	index, err := strconv.Atoi(digits)
+	if err != nil || index < 0 {
+		return 0, p.errorf("invalid list index %q", digits)
+	}
+	return index, nil
+}
@@ -0,0 +1,57 @@
+package maps
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParsePath(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := map[string][]Segment{
+		"foo":                   {{Key: "foo"}},
+		"foo.bar":               {{Key: "foo"}, {Key: "bar"}},
+		`clients."acme.com".id`: {{Key: "clients"}, {Key: "acme.com"}, {Key: "id"}},
+		`clients.acme\.com.id`:  {{Key: "clients"}, {Key: "acme.com"}, {Key: "id"}},
+		`"quoted \"key\""`:      {{Key: `quoted "key"`}},
+		`""`:                    {{Key: ""}},
+		"clients[]":             {{Key: "clients"}, {List: true, Index: -1}},
+		"clients[2].id":         {{Key: "clients"}, {List: true, Index: 2}, {Key: "id"}},
+		"matrix[0][1]":          {{Key: "matrix"}, {List: true, Index: 0}, {List: true, Index: 1}},
+		`escaped\[0]`:           {{Key: "escaped[0]"}},
+	}
+
+	for path, expected := range tests {
+		segments, err := ParsePath(path)
+		g.Expect(err).NotTo(HaveOccurred(), path)
+		g.Expect(segments).To(Equal(expected), path)
+	}
+}
+
+func TestParsePath_invalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	for _, path := range []string{"", "foo.", ".foo", "foo..bar", `"foo`, `foo\`, "foo[1", "foo[x]", "foo[-1]", "foo[1]bar", "[1]"} {
+		_, err := ParsePath(path)
+		g.Expect(err).To(MatchError(ContainSubstring("invalid property path")), path)
+	}
+}
+
+func TestNest(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	segments, err := ParsePath(`clients."acme.com".ids[].value`)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(Nest(segments, "x")).To(Equal(map[interface{}]interface{}{
+		"clients": map[interface{}]interface{}{
+			"acme.com": map[interface{}]interface{}{
+				"ids": Append{map[interface{}]interface{}{"value": "x"}},
+			},
+		},
+	}))
+
+	segments, err = ParsePath("clients[1]")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(Nest(segments, "x")).To(Equal(map[interface{}]interface{}{"clients": Indexed{1: "x"}}))
+}
@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// SecretErrors counts errors caused by the content of a single secret, e.g. keys missing for a template.
+var SecretErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "secret_file_provider_secret_errors_total",
+		Help: "Number of errors caused by the content of a single secret.",
+	},
+	[]string{"namespace", "name", "reason"},
+)
+
+func init() {
+	// Metrics are exposed by the controller runtime metrics endpoint.
+	metrics.Registry.MustRegister(SecretErrors)
+}
@@ -0,0 +1,131 @@
+package namespaces
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/spf13/viper"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Selector selects namespaces by their labels and name. As namespace labels are not part of secrets, the selector keeps
+// track of all matching namespaces, which is updated by watching the namespaces (see [Selector.EventHandler]).
+type Selector struct {
+	labels labels.Selector
+	name   *regexp.Regexp
+
+	mu       sync.RWMutex
+	matching sets.Set[string]
+}
+
+// New creates a selector for the configured namespace label selector and name pattern (see
+// [env.SecretNamespaceLabelSelector] and [env.SecretNamespaceNameSelector]). If both are configured, namespaces have to
+// match both. Returns nil, if neither is configured.
+func New() (*Selector, error) {
+	labelSelector := viper.GetString(env.SecretNamespaceLabelSelector)
+	nameSelector := viper.GetString(env.SecretNamespaceNameSelector)
+	if labelSelector == "" && nameSelector == "" {
+		return nil, nil
+	}
+
+	s := &Selector{labels: labels.Everything(), matching: sets.New[string]()}
+
+	if labelSelector != "" {
+		selector, err := labels.Parse(labelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace label selector: %w", err)
+		}
+		s.labels = selector
+	}
+
+	if nameSelector != "" {
+		regex, err := regexp.CompilePOSIX(nameSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace name selector: %w", err)
+		}
+		s.name = regex
+	}
+
+	return s, nil
+}
+
+// Matches returns true, if the namespace with the given name currently matches. A nil selector matches all namespaces.
+func (s *Selector) Matches(namespace string) bool {
+	if s == nil {
+		return true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.matching.Has(namespace)
+}
+
+// Update re-evaluates the given namespace. Namespaces being deleted no longer match. Returns true, if the namespace
+// started or stopped matching.
+func (s *Selector) Update(namespace client.Object) bool {
+	matches := namespace.GetDeletionTimestamp() == nil &&
+		s.labels.Matches(labels.Set(namespace.GetLabels())) &&
+		(s.name == nil || s.name.MatchString(namespace.GetName()))
+
+	if !matches {
+		return s.Delete(namespace.GetName())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.matching.Has(namespace.GetName()) {
+		return false
+	}
+	s.matching.Insert(namespace.GetName())
+	return true
+}
+
+// Delete marks the namespace with the given name as no longer matching. Returns true, if it matched before.
+func (s *Selector) Delete(namespace string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.matching.Has(namespace) {
+		return false
+	}
+	s.matching.Delete(namespace)
+	return true
+}
+
+// EventHandler returns a handler for namespace events, which updates the selector. Whenever a namespace starts or stops
+// matching, all requests returned by the given function for the namespace are enqueued, so the content of its secrets
+// can be added or removed.
+func (s *Selector) EventHandler(requests func(ctx context.Context, namespace string) []reconcile.Request) handler.EventHandler {
+	enqueue := func(ctx context.Context, namespace string, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+		for _, request := range requests(ctx, namespace) {
+			q.Add(request)
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if s.Update(e.Object) {
+				enqueue(ctx, e.Object.GetName(), q)
+			}
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if s.Update(e.ObjectNew) {
+				enqueue(ctx, e.ObjectNew.GetName(), q)
+			}
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if s.Delete(e.Object.GetName()) {
+				enqueue(ctx, e.Object.GetName(), q)
+			}
+		},
+	}
+}
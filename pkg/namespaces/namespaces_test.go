@@ -0,0 +1,114 @@
+package namespaces
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestNew(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	// no selector configured, all namespaces match
+	s, err := New()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s).To(BeNil())
+	g.Expect(s.Matches("any")).To(BeTrue())
+
+	viper.Set(env.SecretNamespaceLabelSelector, "tenant in (")
+	_, err = New()
+	g.Expect(err).To(MatchError(ContainSubstring("invalid namespace label selector")))
+
+	viper.Set(env.SecretNamespaceLabelSelector, "")
+	viper.Set(env.SecretNamespaceNameSelector, "tenant-(")
+	_, err = New()
+	g.Expect(err).To(MatchError(ContainSubstring("invalid namespace name selector")))
+}
+
+func TestUpdate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretNamespaceLabelSelector, "secret-file-provider=enabled")
+	viper.Set(env.SecretNamespaceNameSelector, "^tenant-")
+	s, err := New()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	tenant := namespace("tenant-a", map[string]string{"secret-file-provider": "enabled"})
+	g.Expect(s.Matches("tenant-a")).To(BeFalse())
+
+	// started matching
+	g.Expect(s.Update(tenant)).To(BeTrue())
+	g.Expect(s.Matches("tenant-a")).To(BeTrue())
+
+	// unchanged
+	g.Expect(s.Update(tenant)).To(BeFalse())
+
+	// stopped matching by label
+	tenant.Labels = nil
+	g.Expect(s.Update(tenant)).To(BeTrue())
+	g.Expect(s.Matches("tenant-a")).To(BeFalse())
+
+	// name does not match
+	g.Expect(s.Update(namespace("other", map[string]string{"secret-file-provider": "enabled"}))).To(BeFalse())
+	g.Expect(s.Matches("other")).To(BeFalse())
+
+	// namespaces being deleted no longer match
+	tenant.Labels = map[string]string{"secret-file-provider": "enabled"}
+	g.Expect(s.Update(tenant)).To(BeTrue())
+	tenant.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	g.Expect(s.Update(tenant)).To(BeTrue())
+	g.Expect(s.Matches("tenant-a")).To(BeFalse())
+}
+
+func TestEventHandler(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretNamespaceNameSelector, "^tenant-")
+	s, err := New()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	h := s.EventHandler(func(_ context.Context, namespace string) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: namespace, Name: "secret"}}}
+	})
+	q := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer q.ShutDown()
+
+	// namespaces not matching are ignored
+	h.Create(context.TODO(), event.CreateEvent{Object: namespace("other", nil)}, q)
+	g.Expect(q.Len()).To(BeZero())
+
+	// secrets of new matching namespaces are enqueued
+	tenant := namespace("tenant-a", nil)
+	h.Create(context.TODO(), event.CreateEvent{Object: tenant}, q)
+	g.Expect(q.Len()).To(Equal(1))
+	request, _ := q.Get()
+	q.Done(request)
+	g.Expect(request.Namespace).To(Equal("tenant-a"))
+
+	// updates not changing the membership are ignored
+	h.Update(context.TODO(), event.UpdateEvent{ObjectOld: tenant, ObjectNew: tenant}, q)
+	g.Expect(q.Len()).To(BeZero())
+
+	// secrets of deleted namespaces are enqueued
+	h.Delete(context.TODO(), event.DeleteEvent{Object: tenant}, q)
+	g.Expect(q.Len()).To(Equal(1))
+	g.Expect(s.Matches("tenant-a")).To(BeFalse())
+}
+
+func namespace(name string, labels map[string]string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+	}
+}
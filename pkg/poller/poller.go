@@ -0,0 +1,100 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Poller periodically reads secrets by name and reconciles them, whenever they changed. In contrast to watching
+// secrets, this only requires permission to *get* the secrets, which can be restricted to their names in a K8s role.
+type Poller struct {
+	// Reader is used to get the secrets. Use an uncached reader, as a cache requires permission to list and watch.
+	Reader client.Reader
+	// Reconciler processes changed secrets.
+	Reconciler reconcile.Reconciler
+	// Filter decides, which changes are reconciled, like the predicates of a watch.
+	Filter predicate.Predicate
+	// Names of the polled secrets.
+	Names []types.NamespacedName
+	// Interval between two polls.
+	Interval time.Duration
+	// Jitter is the maximum factor of the interval, by which each poll is delayed randomly.
+	Jitter float64
+
+	// last seen version of each secret
+	seen map[types.NamespacedName]*corev1.Secret
+}
+
+var _ manager.Runnable = &Poller{}
+
+// Start polling until the given context is done. The first poll happens immediately. The interval has to be positive,
+// otherwise secrets would be polled in a tight loop.
+func (p *Poller) Start(ctx context.Context) error {
+	if p.Interval <= 0 || p.Jitter < 0 {
+		return fmt.Errorf("invalid poll interval %s with jitter %v", p.Interval, p.Jitter)
+	}
+	wait.JitterUntilWithContext(ctx, p.Poll, p.Interval, p.Jitter, true)
+	return nil
+}
+
+// Poll reads all secrets once and reconciles the ones, which were created, changed or deleted since the last poll.
+// Changes are detected by the resource version of the secrets.
+func (p *Poller) Poll(ctx context.Context) {
+	if p.seen == nil {
+		p.seen = make(map[types.NamespacedName]*corev1.Secret)
+	}
+
+	for _, name := range p.Names {
+		secret := &corev1.Secret{}
+		if err := p.Reader.Get(ctx, name, secret); err != nil {
+			if !errors.IsNotFound(err) {
+				slog.Error("failed to poll secret", "namespace", name.Namespace, "name", name.Name, "error", err)
+				continue
+			}
+			secret = nil
+		}
+
+		if p.changed(name, secret) {
+			p.reconcile(ctx, name)
+		}
+	}
+}
+
+// changed remembers the given secret (nil, if it does not exist) and returns true, if it changed since the last poll
+// and passes the filter.
+func (p *Poller) changed(name types.NamespacedName, secret *corev1.Secret) bool {
+	old, known := p.seen[name]
+	if secret == nil {
+		delete(p.seen, name)
+		return known && p.Filter.Delete(event.DeleteEvent{Object: old})
+	}
+
+	p.seen[name] = secret
+	if !known {
+		return p.Filter.Create(event.CreateEvent{Object: secret})
+	}
+	if old.ResourceVersion == secret.ResourceVersion {
+		return false
+	}
+	return p.Filter.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: secret})
+}
+
+func (p *Poller) reconcile(ctx context.Context, name types.NamespacedName) {
+	if _, err := p.Reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: name}); err != nil {
+		// the secret is reconciled again with its next change, or on the next poll, as it is forgotten
+		slog.Error("failed to reconcile polled secret", "namespace", name.Namespace, "name", name.Name, "error", err)
+		delete(p.seen, name)
+	}
+}
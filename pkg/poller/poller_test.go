@@ -0,0 +1,120 @@
+package poller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type recorder struct {
+	requests []reconcile.Request
+	err      error
+}
+
+func (r *recorder) Reconcile(_ context.Context, request reconcile.Request) (reconcile.Result, error) {
+	r.requests = append(r.requests, request)
+	return reconcile.Result{}, r.err
+}
+
+func TestPoll(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	name := types.NamespacedName{Namespace: "a", Name: "foo"}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: name.Namespace, Name: name.Name}}
+	c := fake.NewClientBuilder().WithObjects(secret).Build()
+
+	r := &recorder{}
+	p := &Poller{Reader: c, Reconciler: r, Filter: predicate.Funcs{}, Names: []types.NamespacedName{name, {Namespace: "a", Name: "missing"}}}
+
+	// new secrets are reconciled, missing ones are not
+	p.Poll(context.TODO())
+	g.Expect(r.requests).To(Equal([]reconcile.Request{{NamespacedName: name}}))
+
+	// unchanged secrets are not reconciled again
+	p.Poll(context.TODO())
+	g.Expect(r.requests).To(HaveLen(1))
+
+	// changed secrets are reconciled
+	secret.Data = map[string][]byte{"foo": []byte("bar")}
+	g.Expect(c.Update(context.TODO(), secret)).To(Succeed())
+	p.Poll(context.TODO())
+	g.Expect(r.requests).To(HaveLen(2))
+
+	// deleted secrets are reconciled
+	g.Expect(c.Delete(context.TODO(), secret)).To(Succeed())
+	p.Poll(context.TODO())
+	g.Expect(r.requests).To(HaveLen(3))
+
+	p.Poll(context.TODO())
+	g.Expect(r.requests).To(HaveLen(3))
+}
+
+func TestPollFilter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	name := types.NamespacedName{Namespace: "a", Name: "foo"}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: name.Namespace, Name: name.Name}}
+	c := fake.NewClientBuilder().WithObjects(secret).Build()
+
+	r := &recorder{}
+	filter := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return e.Object.GetLabels()["enabled"] == "true"
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.ObjectNew.GetLabels()["enabled"] == "true"
+		},
+	}
+	p := &Poller{Reader: c, Reconciler: r, Filter: filter, Names: []types.NamespacedName{name}}
+
+	p.Poll(context.TODO())
+	g.Expect(r.requests).To(BeEmpty())
+
+	secret.Labels = map[string]string{"enabled": "true"}
+	g.Expect(c.Update(context.TODO(), secret)).To(Succeed())
+	p.Poll(context.TODO())
+	g.Expect(r.requests).To(HaveLen(1))
+}
+
+func TestPollReconcileError(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	name := types.NamespacedName{Namespace: "a", Name: "foo"}
+	c := fake.NewClientBuilder().WithObjects(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: name.Namespace, Name: name.Name}}).Build()
+
+	r := &recorder{err: context.DeadlineExceeded}
+	p := &Poller{Reader: c, Reconciler: r, Filter: predicate.Funcs{}, Names: []types.NamespacedName{name}}
+
+	// failed reconciliations are retried on the next poll
+	p.Poll(context.TODO())
+	p.Poll(context.TODO())
+	g.Expect(r.requests).To(HaveLen(2))
+
+	r.err = nil
+	p.Poll(context.TODO())
+	p.Poll(context.TODO())
+	g.Expect(r.requests).To(HaveLen(3))
+}
+
+func TestStartInvalidInterval(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	r := &recorder{}
+	for _, p := range []*Poller{
+		{Reconciler: r, Interval: 0},
+		{Reconciler: r, Interval: -time.Second},
+		{Reconciler: r, Interval: time.Second, Jitter: -1},
+	} {
+		g.Expect(p.Start(context.TODO())).To(MatchError(ContainSubstring("invalid poll interval")))
+	}
+	g.Expect(r.requests).To(BeEmpty())
+}
@@ -0,0 +1,52 @@
+package selector
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/logger"
+	"github.com/jaconi-io/secret-file-provider/pkg/templates"
+	"github.com/spf13/viper"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Condition returns true, if the configured condition template (see [env.SecretCondition]) renders to a truthy value
+// for the given secret. Without a condition, every secret is considered. An empty result, "false", "0" or a failing
+// template exclude the secret. Only the allow-listed environment variables (see [env.SecretConditionEnv]) are available
+// in the template.
+func Condition(secret *corev1.Secret) bool {
+	condition := viper.GetString(env.SecretCondition)
+	if condition == "" {
+		return true
+	}
+
+	result, err := templates.RenderCondition(condition, conditionEnv(), secret)
+	if err != nil {
+		// most likely a misconfiguration (e.g. a missing label in strict mode), which must not go unnoticed
+		logger.New(secret).Warn("secret condition failed, excluding secret", "error", err)
+		return false
+	}
+
+	result = strings.TrimSpace(result)
+	if result == "" || result == "<no value>" {
+		return false
+	}
+	if b, err := strconv.ParseBool(result); err == nil {
+		return b
+	}
+	return true
+}
+
+// conditionEnv returns the values of all allow-listed environment variables, which are set.
+func conditionEnv() map[string]string {
+	result := make(map[string]string)
+	for _, name := range env.GetConditionEnv() {
+		if value, ok := os.LookupEnv(name); ok {
+			result[name] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/onsi/gomega"
+	"github.com/spf13/viper"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCondition(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "a",
+			Name:      "foo",
+			Labels:    map[string]string{"stage": "prod", "enabled": "true"},
+		},
+	}
+
+	for _, tt := range []struct {
+		Condition string
+		Result    bool
+	}{
+		{"", true},
+		{`{{ eq .ObjectMeta.Labels.stage "prod" }}`, true},
+		{`{{ eq .ObjectMeta.Labels.stage "dev" }}`, false},
+		{`{{ .ObjectMeta.Labels.enabled }}`, true},
+		{`{{ .ObjectMeta.Labels.missing }}`, false},
+		{`{{ .ObjectMeta.Labels.stage }}`, true},
+		{`{{ if false }}yes{{ end }}`, false},
+		{` 0 `, false},
+		{`{{ required "missing" .ObjectMeta.Labels.missing }}`, false},
+	} {
+		t.Run(tt.Condition, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+
+			defer viper.Reset()
+			viper.Set(env.SecretCondition, tt.Condition)
+
+			g.Expect(Condition(secret.DeepCopy())).To(gomega.Equal(tt.Result))
+		})
+	}
+}
+
+func TestConditionEnv(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+	t.Setenv("SELECTOR_TEST_STAGE", "prod")
+	t.Setenv("SELECTOR_TEST_SECRET", "s3cr3t")
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"stage": "prod"}}}
+	viper.Set(env.SecretCondition, `{{ eq .Labels.stage .Env.SELECTOR_TEST_STAGE }}`)
+
+	// variables are only available, if allow-listed
+	g.Expect(Condition(secret)).To(gomega.BeFalse())
+
+	viper.Set(env.SecretConditionEnv, "SELECTOR_TEST_STAGE")
+	g.Expect(Condition(secret)).To(gomega.BeTrue())
+
+	viper.Set(env.SecretCondition, `{{ .Env.SELECTOR_TEST_SECRET }}`)
+	g.Expect(Condition(secret)).To(gomega.BeFalse())
+}
@@ -0,0 +1,207 @@
+package selector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/spf13/viper"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Selector matches secrets by all configured selectors (see [New]). Secrets have to match every selector, which is set.
+type Selector struct {
+	labels      labels.Selector
+	annotations labels.Selector
+	name        *regexp.Regexp
+	types       sets.Set[string]
+	namespaces  sets.Set[string]
+	polled      []types.NamespacedName
+}
+
+// New creates a selector from the configured label, annotation, name, type and namespace selectors and the polled
+// secret names (see [env.SecretLabelSelector], [env.SecretAnnotationSelector], [env.SecretNameSelector],
+// [env.SecretTypeSelector], [env.SecretNamespaceSelector] and [env.SecretPollNames]).
+func New() (*Selector, error) {
+	s := &Selector{
+		types:      sets.New(env.GetSecretTypes()...),
+		namespaces: sets.New(env.GetNamespaces()...),
+	}
+
+	if labelSelector := viper.GetString(env.SecretLabelSelector); labelSelector != "" {
+		selector, err := parseSelector(labelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret label selector: %w", err)
+		}
+		s.labels = selector
+	}
+
+	if annotationSelector := viper.GetString(env.SecretAnnotationSelector); annotationSelector != "" {
+		selector, err := parseSelector(annotationSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret annotation selector: %w", err)
+		}
+		s.annotations = selector
+	}
+
+	if nameSelector := viper.GetString(env.SecretNameSelector); nameSelector != "" {
+		regex, err := regexp.CompilePOSIX(nameSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret name selector: %w", err)
+		}
+		s.name = regex
+	}
+
+	polled, err := PolledNames()
+	if err != nil {
+		return nil, err
+	}
+	s.polled = polled
+
+	return s, nil
+}
+
+// PolledNames parses the configured names of the polled secrets (see [env.SecretPollNames]). Names without namespace
+// are looked up in the namespace selected by [env.SecretNamespaceSelector], which must be exactly one in that case.
+func PolledNames() ([]types.NamespacedName, error) {
+	namespaces := env.GetNamespaces()
+
+	var names []types.NamespacedName
+	for _, entry := range env.GetPollNames() {
+		namespace, name, ok := strings.Cut(entry, "/")
+		if !ok {
+			if len(namespaces) != 1 {
+				return nil, fmt.Errorf("invalid secret poll names: %q has no namespace and not exactly one namespace is selected", entry)
+			}
+			namespace, name = namespaces[0], entry
+		}
+		if namespace == "" || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid secret poll names: %q; expecting NAME or NAMESPACE/NAME", entry)
+		}
+		names = append(names, types.NamespacedName{Namespace: namespace, Name: name})
+	}
+	return names, nil
+}
+
+// parseSelector parses a selector in Kubernetes label selector syntax.
+func parseSelector(selector string) (labels.Selector, error) {
+	labelSelector, err := metav1.ParseToLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return metav1.LabelSelectorAsSelector(labelSelector)
+}
+
+// IsEmpty returns true, if neither a label, annotation, name nor type selector is set and no secrets are polled.
+// Namespaces alone do not select any secrets.
+func (s *Selector) IsEmpty() bool {
+	return s.labels == nil && s.annotations == nil && s.name == nil && s.types.Len() == 0 && len(s.polled) == 0
+}
+
+// Labels returns the label selector, or nil if none is set.
+func (s *Selector) Labels() labels.Selector {
+	return s.labels
+}
+
+// Matches returns true, if the given object matches all selectors. The type selector only matches secrets.
+func (s *Selector) Matches(object client.Object) bool {
+	if len(s.polled) > 0 && !slices.Contains(s.polled, client.ObjectKeyFromObject(object)) {
+		return false
+	}
+
+	if s.namespaces.Len() > 0 && !s.namespaces.Has(object.GetNamespace()) {
+		return false
+	}
+
+	if s.labels != nil && !s.labels.Matches(labels.Set(object.GetLabels())) {
+		return false
+	}
+
+	if s.annotations != nil && !s.annotations.Matches(labels.Set(object.GetAnnotations())) {
+		return false
+	}
+
+	if s.name != nil && !s.name.MatchString(object.GetName()) {
+		return false
+	}
+
+	if s.types.Len() > 0 {
+		secret, ok := object.(*corev1.Secret)
+		if !ok || !s.types.Has(string(secret.Type)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// List all secrets matching the selector. The label selector is applied by the API server, all others on the client
+// side. If secrets are polled, only these are read one by one, so no permission to list secrets is required.
+func (s *Selector) List(ctx context.Context, c client.Reader) ([]corev1.Secret, error) {
+	if len(s.polled) > 0 {
+		return s.get(ctx, c)
+	}
+
+	listOptions := &client.ListOptions{LabelSelector: s.labels}
+
+	namespaces := sets.List(s.namespaces)
+	if len(namespaces) == 0 {
+		// all namespaces
+		namespaces = []string{""}
+	}
+
+	var result []corev1.Secret
+	for _, ns := range namespaces {
+		secrets := &corev1.SecretList{}
+		if err := c.List(ctx, secrets, listOptions, client.InNamespace(ns)); err != nil {
+			return nil, err
+		}
+
+		for i := range secrets.Items {
+			if s.Matches(&secrets.Items[i]) {
+				result = append(result, secrets.Items[i])
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// get all polled secrets matching the selector. Missing secrets are skipped.
+func (s *Selector) get(ctx context.Context, c client.Reader) ([]corev1.Secret, error) {
+	var result []corev1.Secret
+	for _, name := range s.polled {
+		secret := corev1.Secret{}
+		if err := c.Get(ctx, name, &secret); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if s.Matches(&secret) {
+			result = append(result, secret)
+		}
+	}
+
+	return result, nil
+}
+
+// Secrets lists all secrets matching the configured selectors. See [New].
+func Secrets(ctx context.Context, c client.Reader) ([]corev1.Secret, error) {
+	s, err := New()
+	if err != nil {
+		return nil, err
+	}
+	return s.List(ctx, c)
+}
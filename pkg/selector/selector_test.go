@@ -0,0 +1,226 @@
+package selector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNew(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	s, err := New()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s.IsEmpty()).To(BeTrue())
+
+	// namespaces alone do not select secrets
+	viper.Set(env.SecretNamespaceSelector, "a")
+	s, err = New()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s.IsEmpty()).To(BeTrue())
+
+	viper.Set(env.SecretTypeSelector, "kubernetes.io/tls")
+	s, err = New()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s.IsEmpty()).To(BeFalse())
+
+	viper.Set(env.SecretLabelSelector, "foo=bar=42")
+	_, err = New()
+	g.Expect(err).To(MatchError("invalid secret label selector: couldn't parse the selector string \"foo=bar=42\": found '=', expected: ',' or 'end of string'"))
+
+	viper.Set(env.SecretLabelSelector, "")
+	viper.Set(env.SecretAnnotationSelector, "foo in (")
+	_, err = New()
+	g.Expect(err).To(MatchError(ContainSubstring("invalid secret annotation selector")))
+
+	viper.Set(env.SecretAnnotationSelector, "")
+	viper.Set(env.SecretNameSelector, "[")
+	_, err = New()
+	g.Expect(err).To(MatchError("invalid secret name selector: error parsing regexp: missing closing ]: `[`"))
+}
+
+func TestMatchesLabels(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretLabelSelector, "foo in (bar, baz)")
+	s, err := New()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(s.Matches(testSecret("a", "", map[string]string{"foo": "bar"}))).To(BeTrue())
+	g.Expect(s.Matches(testSecret("a", "", map[string]string{"foo": "baz"}))).To(BeTrue())
+	g.Expect(s.Matches(testSecret("a", "", map[string]string{"foo": "42"}))).To(BeFalse())
+}
+
+func TestMatchesAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretAnnotationSelector, "env=prod,!skip")
+	s, err := New()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secret := testSecret("a", "", nil)
+	secret.Annotations = map[string]string{"env": "prod"}
+	g.Expect(s.Matches(secret)).To(BeTrue())
+
+	secret.Annotations["skip"] = "true"
+	g.Expect(s.Matches(secret)).To(BeFalse())
+
+	secret.Annotations = map[string]string{"env": "dev"}
+	g.Expect(s.Matches(secret)).To(BeFalse())
+}
+
+func TestMatchesName(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretNameSelector, "^foo-bar-.*$")
+	s, err := New()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(s.Matches(testSecret("a", "foo-bar-", nil))).To(BeTrue())
+	g.Expect(s.Matches(testSecret("a", "foo-bar-1", nil))).To(BeTrue())
+	g.Expect(s.Matches(testSecret("a", "foo-bar----_", nil))).To(BeTrue())
+	g.Expect(s.Matches(testSecret("a", "foo-bar", nil))).To(BeFalse())
+	g.Expect(s.Matches(testSecret("a", "ffoo-bar-", nil))).To(BeFalse())
+}
+
+func TestMatchesType(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretTypeSelector, "kubernetes.io/tls, kubernetes.io/basic-auth")
+	s, err := New()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secret := testSecret("a", "foo", nil)
+	secret.Type = corev1.SecretTypeTLS
+	g.Expect(s.Matches(secret)).To(BeTrue())
+
+	secret.Type = corev1.SecretTypeBasicAuth
+	g.Expect(s.Matches(secret)).To(BeTrue())
+
+	secret.Type = corev1.SecretTypeOpaque
+	g.Expect(s.Matches(secret)).To(BeFalse())
+
+	// the type of objects other than secrets is unknown
+	g.Expect(s.Matches(&metav1.PartialObjectMetadata{})).To(BeFalse())
+}
+
+func TestMatchesNamespace(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	viper.Set(env.SecretNameSelector, ".*")
+	s, err := New()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s.Matches(testSecret("foo", "", nil))).To(BeTrue())
+	g.Expect(s.Matches(testSecret("bar", "", nil))).To(BeTrue())
+
+	viper.Set(env.SecretNamespaceSelector, "foo")
+	s, err = New()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s.Matches(testSecret("foo", "", nil))).To(BeTrue())
+	g.Expect(s.Matches(testSecret("bar", "", nil))).To(BeFalse())
+
+	viper.Set(env.SecretNamespaceSelector, "foo, bar")
+	s, err = New()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s.Matches(testSecret("foo", "", nil))).To(BeTrue())
+	g.Expect(s.Matches(testSecret("bar", "", nil))).To(BeTrue())
+	g.Expect(s.Matches(testSecret("baz", "", nil))).To(BeFalse())
+}
+
+func TestSecrets(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	c := fake.NewClientBuilder().WithObjects(
+		testSecret("a", "foo-1", map[string]string{"foo": "bar"}),
+		testSecret("a", "bar-1", map[string]string{"foo": "bar"}),
+		testSecret("b", "foo-2", map[string]string{"foo": "baz"}),
+		testSecret("c", "foo-3", map[string]string{"foo": "bar"}),
+	).Build()
+
+	viper.Set(env.SecretNameSelector, "foo-.*")
+	g.Expect(Secrets(context.Background(), c)).To(HaveLen(3))
+
+	viper.Set(env.SecretNamespaceSelector, "a,b")
+	g.Expect(Secrets(context.Background(), c)).To(HaveLen(2))
+
+	viper.Set(env.SecretLabelSelector, "foo=bar")
+	secrets, err := Secrets(context.Background(), c)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secrets).To(HaveLen(1))
+	g.Expect(secrets[0].Name).To(Equal("foo-1"))
+
+	viper.Set(env.SecretNameSelector, "[")
+	_, err = Secrets(context.Background(), c)
+	g.Expect(err).To(MatchError("invalid secret name selector: error parsing regexp: missing closing ]: `[`"))
+}
+
+func TestPolledNames(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	g.Expect(PolledNames()).To(BeEmpty())
+
+	viper.Set(env.SecretPollNames, "a/foo, b/bar")
+	g.Expect(PolledNames()).To(Equal([]types.NamespacedName{{Namespace: "a", Name: "foo"}, {Namespace: "b", Name: "bar"}}))
+
+	viper.Set(env.SecretPollNames, "foo")
+	_, err := PolledNames()
+	g.Expect(err).To(MatchError(`invalid secret poll names: "foo" has no namespace and not exactly one namespace is selected`))
+
+	viper.Set(env.SecretNamespaceSelector, "a")
+	g.Expect(PolledNames()).To(Equal([]types.NamespacedName{{Namespace: "a", Name: "foo"}}))
+
+	viper.Set(env.SecretPollNames, "a/foo/bar")
+	_, err = PolledNames()
+	g.Expect(err).To(MatchError(`invalid secret poll names: "a/foo/bar"; expecting NAME or NAMESPACE/NAME`))
+}
+
+func TestSecretsPolled(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	c := fake.NewClientBuilder().WithObjects(
+		testSecret("a", "foo-1", map[string]string{"foo": "bar"}),
+		testSecret("a", "foo-2", map[string]string{"foo": "baz"}),
+		testSecret("b", "foo-3", map[string]string{"foo": "bar"}),
+	).Build()
+
+	viper.Set(env.SecretPollNames, "a/foo-1,a/foo-2,a/missing")
+	s, err := New()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s.IsEmpty()).To(BeFalse())
+	g.Expect(s.Matches(testSecret("b", "foo-3", nil))).To(BeFalse())
+
+	// polled secrets are read by name, missing ones are skipped
+	g.Expect(Secrets(context.Background(), c)).To(HaveLen(2))
+
+	viper.Set(env.SecretLabelSelector, "foo=bar")
+	secrets, err := Secrets(context.Background(), c)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secrets).To(HaveLen(1))
+	g.Expect(secrets[0].Name).To(Equal("foo-1"))
+}
+
+func testSecret(namespace, name string, labels map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    labels,
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package setup
+
+import (
+	"github.com/jaconi-io/secret-file-provider/pkg/apis/v1alpha1"
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/filemapping"
+	"github.com/jaconi-io/secret-file-provider/pkg/selector"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CacheOptions returns the cache configuration for the manager. Secrets are filtered by the API server using the
+// label selector, so only matching secrets are kept in memory. If namespaces are selected, only these namespaces are
+// watched, so K8s roles are sufficient instead of cluster roles. Managed fields are never read and therefore stripped.
+func CacheOptions() (cache.Options, error) {
+	secretSelector, err := selector.New()
+	if err != nil {
+		return cache.Options{}, err
+	}
+
+	options := cache.Options{
+		DefaultTransform: cache.TransformStripManagedFields(),
+	}
+
+	options.ByObject = make(map[client.Object]cache.ByObject)
+	if labels := secretSelector.Labels(); labels != nil {
+		options.ByObject[&corev1.Secret{}] = cache.ByObject{Label: labels}
+	}
+
+	if filemapping.Configured() {
+		// only the configured mapping is watched, so access can be restricted to its name
+		name, err := filemapping.Name()
+		if err != nil {
+			return cache.Options{}, err
+		}
+		options.ByObject[&v1alpha1.SecretFileMapping{}] = cache.ByObject{
+			Namespaces: map[string]cache.Config{
+				name.Namespace: {FieldSelector: fields.OneTermEqualSelector("metadata.name", name.Name)},
+			},
+		}
+	}
+
+	if namespaces := env.GetNamespaces(); len(namespaces) > 0 {
+		options.DefaultNamespaces = make(map[string]cache.Config, len(namespaces))
+		for _, ns := range namespaces {
+			options.DefaultNamespaces[ns] = cache.Config{}
+		}
+	}
+
+	return options, nil
+}
+
+// ClientOptions returns the client configuration for the manager. Polled secrets (see [env.SecretPollNames]) are read
+// directly from the API server, as caching them requires permission to list and watch secrets.
+func ClientOptions() client.Options {
+	if len(env.GetPollNames()) == 0 {
+		return client.Options{}
+	}
+
+	return client.Options{
+		Cache: &client.CacheOptions{
+			DisableFor: []client.Object{&corev1.Secret{}},
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+
+	"github.com/onsi/gomega"
+	"github.com/spf13/viper"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+func TestCacheOptions(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	viper.Set(env.SecretLabelSelector, "foo=bar")
+	viper.Set(env.SecretNamespaceSelector, "a, b")
+
+	options, err := CacheOptions()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(options.DefaultNamespaces).To(gomega.Equal(map[string]cache.Config{"a": {}, "b": {}}))
+	g.Expect(options.ByObject).To(gomega.HaveLen(1))
+	for object, byObject := range options.ByObject {
+		g.Expect(object).To(gomega.BeAssignableToTypeOf(&v1.Secret{}))
+		g.Expect(byObject.Label.Matches(labels.Set{"foo": "bar"})).To(gomega.BeTrue())
+		g.Expect(byObject.Label.Matches(labels.Set{"foo": "baz"})).To(gomega.BeFalse())
+	}
+
+	// managed fields are stripped
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}}}
+	transformed, err := options.DefaultTransform(secret)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(transformed.(*v1.Secret).ManagedFields).To(gomega.BeNil())
+}
+
+func TestCacheOptionsNoLabelSelector(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	viper.Set(env.SecretNameSelector, "foo-.*")
+
+	options, err := CacheOptions()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(options.ByObject).To(gomega.BeEmpty())
+	g.Expect(options.DefaultNamespaces).To(gomega.BeEmpty())
+}
+
+func TestCacheOptionsInvalidLabelSelector(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	viper.Set(env.SecretLabelSelector, "foo=bar=42")
+
+	_, err := CacheOptions()
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("invalid secret label selector")))
+}
+
+func TestClientOptions(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	g.Expect(ClientOptions().Cache).To(gomega.BeNil())
+
+	viper.Set(env.SecretPollNames, "a/foo")
+	g.Expect(ClientOptions().Cache.DisableFor).To(gomega.ConsistOf(&v1.Secret{}))
+}
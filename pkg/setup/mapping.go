@@ -0,0 +1,105 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/apis/v1alpha1"
+	"github.com/jaconi-io/secret-file-provider/pkg/controllers/secrets"
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/filemapping"
+	"github.com/jaconi-io/secret-file-provider/pkg/finalizer"
+	"github.com/jaconi-io/secret-file-provider/pkg/logger"
+	"github.com/jaconi-io/secret-file-provider/pkg/selector"
+
+	"github.com/spf13/viper"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ApplyMapping reads the configured mapping (see [filemapping.Configured]) and applies it to the configuration. If the
+// configuration is invalid afterward, the previous configuration is restored. The result is reported in the status of
+// the mapping. Returns the generation of the mapping, even if it could not be applied.
+func ApplyMapping(ctx context.Context, c client.Client, validate func() error) (int64, error) {
+	mapping, err := filemapping.Get(ctx, c)
+	if err != nil {
+		return 0, err
+	}
+
+	previous := filemapping.Apply(&mapping.Spec)
+	applyErr := validate()
+	if applyErr != nil {
+		filemapping.Restore(previous)
+	}
+
+	if err := filemapping.UpdateStatus(ctx, c, mapping, applyErr); err != nil {
+		slog.Error("failed to update mapping status", "error", err)
+	}
+
+	if applyErr != nil {
+		return mapping.Generation, applyErr
+	}
+	slog.Info("applied mapping", "namespace", mapping.Namespace, "name", mapping.Name, "generation", mapping.Generation)
+	return mapping.Generation, nil
+}
+
+// removeContent removes the content of the given secrets, before the configuration changes. See [secrets.Remove].
+func removeContent(list []corev1.Secret) error {
+	var errs []error
+	for i := range list {
+		if err := secrets.Remove(&list[i]); err != nil {
+			logger.New(&list[i]).Error("failed to remove content", "error", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Reconfigure changes the configuration by calling apply, while no controller is running. The content of all matching
+// secrets is removed with the previous configuration and written with the new one right away, instead of waiting for
+// the controllers to start, so the files lack content only during a single pass over the secrets. Finalizers are kept
+// on secrets, which still match, and removed from all others.
+func Reconfigure(ctx context.Context, c client.Client, apply func()) error {
+	previous, err := selector.Secrets(ctx, c)
+	if err != nil {
+		return err
+	}
+	errs := []error{removeContent(previous)}
+
+	apply()
+
+	matching, err := reconcileAll(ctx, c)
+	errs = append(errs, err)
+	if matching != nil {
+		var stale []corev1.Secret
+		for i := range previous {
+			if !viper.GetBool(env.SecretDeletionWatch) || !matching.Has(client.ObjectKeyFromObject(&previous[i])) {
+				stale = append(stale, previous[i])
+			}
+		}
+		finalizer.RemoveFrom(ctx, c, stale)
+	}
+	return errors.Join(errs...)
+}
+
+// RegisterMappingController registers a controller watching the configured mapping. Once it differs from the applied
+// generation, changed is called. Otherwise, the given results are reported in its status.
+func RegisterMappingController(mgr manager.Manager, generation int64, changed func(), results *filemapping.Results) error {
+	name, err := filemapping.Name()
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("secretfilemapping").
+		For(&v1alpha1.SecretFileMapping{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+			return client.ObjectKeyFromObject(object) == name
+		}))).
+		Complete(&filemapping.Reconciler{Client: mgr.GetClient(), Generation: generation, Changed: changed, Results: results})
+}
@@ -0,0 +1,98 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/apis/v1alpha1"
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+
+	"github.com/onsi/gomega"
+	"github.com/spf13/viper"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestApplyMapping(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	viper.Set(env.MappingName, "foo")
+	viper.Set(env.MappingNamespace, "a")
+	viper.Set(env.SecretLabelSelector, "env=default")
+
+	s := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(s)).To(gomega.Succeed())
+	g.Expect(v1alpha1.AddToScheme(s)).To(gomega.Succeed())
+
+	mapping := &v1alpha1.SecretFileMapping{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "foo", Generation: 2},
+		Spec:       v1alpha1.SecretFileMappingSpec{Selector: v1alpha1.SecretSelector{Label: "env=prod"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(mapping).WithStatusSubresource(mapping).Build()
+	name := types.NamespacedName{Namespace: "a", Name: "foo"}
+
+	// invalid mappings are reported and not applied
+	generation, err := ApplyMapping(context.TODO(), c, func() error {
+		return errors.New("invalid")
+	})
+	g.Expect(err).To(gomega.MatchError("invalid"))
+	g.Expect(generation).To(gomega.BeEquivalentTo(2))
+	g.Expect(viper.GetString(env.SecretLabelSelector)).To(gomega.Equal("env=default"))
+
+	g.Expect(c.Get(context.TODO(), name, mapping)).To(gomega.Succeed())
+	g.Expect(mapping.Status.ObservedGeneration).To(gomega.BeEquivalentTo(2))
+	g.Expect(mapping.Status.LastError).To(gomega.Equal("invalid"))
+
+	generation, err = ApplyMapping(context.TODO(), c, func() error {
+		return nil
+	})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(generation).To(gomega.BeEquivalentTo(2))
+	g.Expect(viper.GetString(env.SecretLabelSelector)).To(gomega.Equal("env=prod"))
+
+	g.Expect(c.Get(context.TODO(), name, mapping)).To(gomega.Succeed())
+	g.Expect(mapping.Status.LastError).To(gomega.BeEmpty())
+}
+
+func TestReconfigure(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	filename := filepath.Join(t.TempDir(), "secrets.yaml")
+	viper.Set(env.PodName, "foo")
+	viper.Set(env.SecretLabelSelector, "company")
+	viper.Set(env.SecretFileNamePattern, filename)
+	viper.Set(env.SecretFilePropertyPattern, "{{.ObjectMeta.Labels.company}}")
+	viper.Set(env.SecretDeletionWatch, true)
+
+	acme, other := oneshotSecret("acme"), oneshotSecret("other")
+	acme.Finalizers = []string{env.GetFinalizer()}
+	other.Finalizers = []string{env.GetFinalizer()}
+	c := fake.NewClientBuilder().WithObjects(acme, other).Build()
+
+	// the content is written with the new configuration right away
+	g.Expect(Reconfigure(context.TODO(), c, func() {
+		viper.Set(env.SecretLabelSelector, "company=acme")
+		viper.Set(env.SecretFilePropertyPattern, "new.{{.ObjectMeta.Labels.company}}")
+	})).To(gomega.Succeed())
+
+	content, err := os.ReadFile(filename)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(content)).To(gomega.Equal("new:\n  acme:\n    key: value\n"))
+
+	// finalizers are kept on secrets, which still match, only
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "a", Name: "acme"}, acme)).To(gomega.Succeed())
+	g.Expect(acme.Finalizers).To(gomega.ConsistOf(env.GetFinalizer()))
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "a", Name: "other"}, other)).To(gomega.Succeed())
+	g.Expect(other.Finalizers).To(gomega.BeEmpty())
+}
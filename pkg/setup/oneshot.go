@@ -0,0 +1,86 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/controllers/secrets"
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/namespaces"
+	"github.com/jaconi-io/secret-file-provider/pkg/selector"
+
+	"github.com/spf13/viper"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// RunOnce processes all matching secrets once and returns, e.g. to provide the files in an init container before the
+// application starts (see [env.Oneshot]). Neither finalizers are added nor callbacks are called, as there is no sidecar
+// cleaning up afterwards and the application is not running yet.
+func RunOnce(ctx context.Context, c client.Client) error {
+	viper.Set(env.SecretDeletionWatch, false)
+	viper.Set(env.CallbackURL, "")
+
+	_, err := reconcileAll(ctx, c)
+	return err
+}
+
+// reconcileAll reconciles all matching secrets once with the current configuration. Returns the matching secrets, or
+// nil, if they could not be listed.
+func reconcileAll(ctx context.Context, c client.Client) (sets.Set[types.NamespacedName], error) {
+	filter, err := createFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceSelector, err := namespaces.New()
+	if err != nil {
+		return nil, err
+	}
+	if namespaceSelector != nil {
+		list := &corev1.NamespaceList{}
+		if err := c.List(ctx, list); err != nil {
+			return nil, fmt.Errorf("failed to list namespaces: %w", err)
+		}
+		for i := range list.Items {
+			namespaceSelector.Update(&list.Items[i])
+		}
+	}
+
+	list, err := selector.Secrets(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	reconciler := newReconciler(c, nil, namespaceSelector, nil)
+
+	processed := sets.New[types.NamespacedName]()
+	var errs []error
+	if fileReconciler, ok := reconciler.(*secrets.FileReconciler); ok {
+		// the template file has to exist, even if no secret matches
+		if err := fileReconciler.Render(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("template file: %w", err))
+		}
+	}
+	for i := range list {
+		if !filter.Create(event.CreateEvent{Object: &list[i]}) {
+			continue
+		}
+
+		request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list[i])}
+		processed.Insert(request.NamespacedName)
+		if _, err := reconciler.Reconcile(ctx, request); err != nil {
+			errs = append(errs, fmt.Errorf("secret %s: %w", request.NamespacedName, err))
+		}
+	}
+
+	slog.Info("processed all secrets once", "count", processed.Len(), "errors", len(errs))
+	return processed, errors.Join(errs...)
+}
@@ -0,0 +1,77 @@
+package setup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+
+	"github.com/onsi/gomega"
+	"github.com/spf13/viper"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunOnce(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	filename := filepath.Join(t.TempDir(), "secrets.yaml")
+	viper.Set(env.SecretLabelSelector, "company")
+	viper.Set(env.SecretCondition, `{{ ne .ObjectMeta.Labels.company "initech" }}`)
+	viper.Set(env.SecretFileNamePattern, filename)
+	viper.Set(env.SecretFilePropertyPattern, "{{.ObjectMeta.Labels.company}}")
+	viper.Set(env.SecretDeletionWatch, true)
+	viper.Set(env.PodName, "pod1")
+
+	c := fake.NewClientBuilder().WithObjects(
+		oneshotSecret("acme"),
+		oneshotSecret("initech"),
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "unlabeled"}, Data: map[string][]byte{"key": []byte("value")}},
+	).Build()
+
+	g.Expect(RunOnce(context.TODO(), c)).To(gomega.Succeed())
+
+	content, err := os.ReadFile(filename)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(content)).To(gomega.Equal("acme:\n  key: value\n"))
+
+	// no finalizers are added, as nobody would remove them
+	secret := &v1.Secret{}
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "a", Name: "acme"}, secret)).To(gomega.Succeed())
+	g.Expect(secret.Finalizers).To(gomega.BeEmpty())
+}
+
+func oneshotSecret(company string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: company, Labels: map[string]string{"company": company}},
+		Data:       map[string][]byte{"key": []byte("value")},
+	}
+}
+
+func TestRunOnceTemplateFileWithoutSecrets(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	dir := t.TempDir()
+	templateFile := filepath.Join(dir, "template")
+	filename := filepath.Join(dir, "clients.conf")
+	g.Expect(os.WriteFile(templateFile, []byte("# clients\n{{range .Secrets}}{{.Name}}\n{{end}}"), 0644)).To(gomega.Succeed())
+	viper.Set(env.TemplateFile, templateFile)
+	viper.Set(env.SecretLabelSelector, "company")
+	viper.Set(env.SecretFileNamePattern, filename)
+
+	// the file is rendered, even though no secret matches
+	g.Expect(RunOnce(context.TODO(), fake.NewClientBuilder().Build())).To(gomega.Succeed())
+
+	content, err := os.ReadFile(filename)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(content)).To(gomega.Equal("# clients\n"))
+}
@@ -0,0 +1,30 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+
+	"github.com/spf13/viper"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// OwnPod reads the pod the sidecar runs in, whose annotations may override the configuration (see
+// [env.ApplyAnnotations]). Returns nil, if the namespace of the pod (see [env.PodNamespace]) is not set.
+func OwnPod(ctx context.Context, c client.Reader) (*corev1.Pod, error) {
+	namespace := viper.GetString(env.PodNamespace)
+	if namespace == "" {
+		return nil, nil
+	}
+
+	pod := &corev1.Pod{}
+	name := types.NamespacedName{Namespace: namespace, Name: viper.GetString(env.PodName)}
+	if err := c.Get(ctx, name, pod); err != nil {
+		return nil, fmt.Errorf("failed to read pod %s: %w", name, err)
+	}
+	return pod, nil
+}
@@ -0,0 +1,42 @@
+package setup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+
+	"github.com/onsi/gomega"
+	"github.com/spf13/viper"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestOwnPod(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	c := fake.NewClientBuilder().WithObjects(&v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "a",
+		Name:        "pod1",
+		Annotations: map[string]string{env.ConfigAnnotationPrefix + env.SecretLabelSelector: "foo=bar"},
+	}}).Build()
+
+	// without namespace, the pod is not read
+	viper.Set(env.PodName, "pod1")
+	pod, err := OwnPod(context.TODO(), c)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(pod).To(gomega.BeNil())
+
+	viper.Set(env.PodNamespace, "a")
+	pod, err = OwnPod(context.TODO(), c)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(pod.Annotations).To(gomega.HaveKeyWithValue(env.ConfigAnnotationPrefix+env.SecretLabelSelector, "foo=bar"))
+
+	viper.Set(env.PodName, "pod2")
+	_, err = OwnPod(context.TODO(), c)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("failed to read pod a/pod2")))
+}
@@ -1,112 +1,172 @@
 package setup
 
 import (
+	"context"
 	"errors"
 	"log/slog"
-	"regexp"
-	"strings"
 
 	"github.com/jaconi-io/secret-file-provider/pkg/controllers/secrets"
 	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/jaconi-io/secret-file-provider/pkg/filemapping"
+	"github.com/jaconi-io/secret-file-provider/pkg/logger"
+	"github.com/jaconi-io/secret-file-provider/pkg/namespaces"
+	"github.com/jaconi-io/secret-file-provider/pkg/poller"
+	"github.com/jaconi-io/secret-file-provider/pkg/selector"
 
 	"github.com/spf13/viper"
 
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-func RegisterControllers(mgr manager.Manager) error {
+// RegisterControllers registers the controllers for the configured secrets. The result of syncing each secret is
+// recorded in the given results, which may be nil.
+func RegisterControllers(mgr manager.Manager, results *filemapping.Results) error {
 	filter, err := createFilter()
 	if err != nil {
 		return err
 	}
 
-	slog.Info("registering secret controller")
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Secret{}).
-		WithEventFilter(filter).
-		Complete(&secrets.Reconciler{Client: mgr.GetClient()})
-}
-
-// createFilter creates secret read filters based on either name / namespace or label selector.
-func createFilter() (predicate.Predicate, error) {
-	labelSelector := viper.GetString(env.SecretLabelSelector)
-	nameSelector := viper.GetString(env.SecretNameSelector)
-	namespaceSelector := strings.Split(viper.GetString(env.SecretNamespaceSelector), ",")
-
-	if labelSelector != "" && nameSelector != "" {
-		return nil, errors.New("name and label selector are set")
+	namespaceSelector, err := namespaces.New()
+	if err != nil {
+		return err
 	}
 
-	if labelSelector != "" {
-		labelSelectorPredicate, err := matchByLabelSelector(labelSelector)
-		if err != nil {
-			return nil, err
+	reconciler := newReconciler(mgr.GetClient(), mgr.GetEventRecorder("secret-file-provider"), namespaceSelector, results)
+	if fileReconciler, ok := reconciler.(*secrets.FileReconciler); ok {
+		// the template file has to exist, even if no secret matches
+		if err := mgr.Add(fileReconciler); err != nil {
+			return err
 		}
-
-		namespacePredicate := matchByNamespace(namespaceSelector)
-
-		return predicate.And(matchRelevantEvents(), namespacePredicate, labelSelectorPredicate), nil
 	}
 
-	if nameSelector != "" {
-		namePredicate, err := matchByName(nameSelector)
+	if polled := env.GetPollNames(); len(polled) > 0 {
+		if namespaceSelector != nil {
+			return errors.New("polling secrets cannot be combined with namespace label or name selectors")
+		}
+
+		names, err := selector.PolledNames()
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		namespacePredicate := matchByNamespace(namespaceSelector)
+		slog.Info("polling secrets", "names", polled)
+		return mgr.Add(&poller.Poller{
+			Reader:     mgr.GetAPIReader(),
+			Reconciler: reconciler,
+			Filter:     filter,
+			Names:      names,
+			Interval:   viper.GetDuration(env.SecretPollInterval),
+			Jitter:     viper.GetFloat64(env.SecretPollJitter),
+		})
+	}
 
-		return predicate.And(matchRelevantEvents(), namePredicate, namespacePredicate), nil
+	if namespaceSelector == nil {
+		return ctrl.NewControllerManagedBy(mgr).
+			For(&corev1.Secret{}, builder.WithPredicates(filter)).
+			Complete(reconciler)
 	}
 
-	return nil, errors.New("no secret selector set")
+	// Namespaces are watched to add or remove the content of their secrets, whenever they start or stop matching. Only
+	// the metadata of namespaces is cached, as the labels are all we need.
+	slog.Info("watching namespaces")
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Secret{}, builder.WithPredicates(filter, matchByNamespaceSelector(namespaceSelector))).
+		WatchesMetadata(&corev1.Namespace{}, namespaceSelector.EventHandler(secretsIn(mgr.GetClient(), filter))).
+		Complete(reconciler)
 }
 
-// matchByLabelSelector returns a predicate matching objects by a Kubernetes label selector.
-func matchByLabelSelector(selectFilter string) (predicate.Predicate, error) {
-	selector, err := metav1.ParseToLabelSelector(selectFilter)
-	if err != nil {
-		return nil, err
+// newReconciler creates the reconciler for the configured mode: rendering a template file with all secrets, or mapping
+// the content of each secret.
+func newReconciler(c client.Client, recorder events.EventRecorder, namespaceSelector *namespaces.Selector,
+	results *filemapping.Results) reconcile.Reconciler {
+	if viper.GetString(env.TemplateFile) != "" {
+		slog.Info("registering secret template file controller")
+		return &secrets.FileReconciler{Client: c, Recorder: recorder, Namespaces: namespaceSelector, Results: results}
 	}
 
-	return predicate.LabelSelectorPredicate(*selector)
+	slog.Info("registering secret controller")
+	return &secrets.Reconciler{Client: c, Recorder: recorder, Namespaces: namespaceSelector, Results: results}
+}
+
+// secretsIn returns a function listing reconcile requests for all secrets in a namespace, which match the given filter.
+func secretsIn(c client.Reader, filter predicate.Predicate) func(context.Context, string) []reconcile.Request {
+	return func(ctx context.Context, namespace string) []reconcile.Request {
+		list := &corev1.SecretList{}
+		if err := c.List(ctx, list, client.InNamespace(namespace)); err != nil {
+			slog.Error("failed to list secrets", "namespace", namespace, "error", err)
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for i := range list.Items {
+			if filter.Create(event.CreateEvent{Object: &list.Items[i]}) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+			}
+		}
+		return requests
+	}
 }
 
-// matchByName returns a predicate matching objects name, using a regular expression.
-func matchByName(regexString string) (predicate.Predicate, error) {
-	regex, err := regexp.CompilePOSIX(regexString)
+// createFilter creates secret read filters based on the combined label, annotation, name, type and namespace selectors
+// and the secret condition.
+func createFilter() (predicate.Predicate, error) {
+	secretSelector, err := selector.New()
 	if err != nil {
 		return nil, err
 	}
 
-	return predicate.NewPredicateFuncs(func(object client.Object) bool {
-		return regex.Match([]byte(object.GetName()))
-	}), nil
+	if secretSelector.IsEmpty() {
+		return nil, errors.New("no secret selector set")
+	}
+
+	return predicate.And(matchRelevantEvents(), predicate.NewPredicateFuncs(secretSelector.Matches), matchByCondition()), nil
 }
 
-// matchByNamespace returns a predicate matching an object by its namespace. If the list of namespaces is empty, match
-// all objects.
-func matchByNamespace(namespaces []string) predicate.Predicate {
-	if len(namespaces) == 0 {
-		return predicate.NewPredicateFuncs(func(object client.Object) bool {
-			return true
-		})
+// matchByCondition returns a predicate matching secrets, for which the condition template is truthy (see
+// [selector.Condition]). Updates are passed on, if either the old or the new secret matches, so the content of secrets
+// no longer matching gets removed by the reconciler.
+func matchByCondition() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return matchesCondition(e.Object)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return matchesCondition(e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return matchesCondition(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			matchedBefore, matches := matchesCondition(e.ObjectOld), matchesCondition(e.ObjectNew)
+			if matchedBefore && !matches {
+				if secret, ok := e.ObjectNew.(*corev1.Secret); ok {
+					logger.New(secret).Info("secret no longer matches condition, removing its content")
+				}
+			}
+			return matchedBefore || matches
+		},
 	}
+}
 
-	namespaceMap := map[string]struct{}{}
-	for _, namespace := range namespaces {
-		namespaceMap[namespace] = struct{}{}
-	}
+func matchesCondition(object client.Object) bool {
+	secret, ok := object.(*corev1.Secret)
+	return ok && selector.Condition(secret)
+}
 
+// matchByNamespaceSelector returns a predicate matching objects in namespaces currently selected by the given selector.
+// Events for secrets in other namespaces are dropped, but their content is removed once their namespace stops matching
+// (see [namespaces.Selector.EventHandler]).
+func matchByNamespaceSelector(selector *namespaces.Selector) predicate.Predicate {
 	return predicate.NewPredicateFuncs(func(object client.Object) bool {
-		_, ok := namespaceMap[object.GetNamespace()]
-		return ok
+		return selector.Matches(object.GetNamespace())
 	})
 }
 
@@ -118,7 +178,8 @@ func matchRelevantEvents() predicate.Predicate {
 			return true
 		},
 		DeleteFunc: func(_ event.DeleteEvent) bool {
-			return viper.GetBool(env.SecretDeletionWatch)
+			// template files are rendered with all remaining secrets, so deletions always have to be considered
+			return viper.GetBool(env.SecretDeletionWatch) || viper.GetString(env.TemplateFile) != ""
 		},
 		GenericFunc: func(_ event.GenericEvent) bool {
 			return false
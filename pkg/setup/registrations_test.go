@@ -1,6 +1,7 @@
 package setup
 
 import (
+	"context"
 	"testing"
 
 	"github.com/jaconi-io/secret-file-provider/pkg/env"
@@ -10,10 +11,15 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 func TestRegisterControllersNoConfiguration(t *testing.T) {
@@ -22,23 +28,23 @@ func TestRegisterControllersNoConfiguration(t *testing.T) {
 	mgr, err := ctrl.NewManager(&rest.Config{}, manager.Options{})
 	g.Expect(err).To(gomega.BeNil())
 
-	err = RegisterControllers(mgr)
+	err = RegisterControllers(mgr, nil)
 	g.Expect(err).To(gomega.MatchError("no secret selector set"))
 }
 
-func TestRegisterControllersTooMuchConfiguration(t *testing.T) {
+func TestRegisterControllersInvalidConfiguration(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 
 	defer viper.Reset()
 
 	viper.Set(env.SecretLabelSelector, "foo=bar")
-	viper.Set(env.SecretNameSelector, ".*")
+	viper.Set(env.SecretNameSelector, "[")
 
 	mgr, err := ctrl.NewManager(&rest.Config{}, manager.Options{})
 	g.Expect(err).To(gomega.BeNil())
 
-	err = RegisterControllers(mgr)
-	g.Expect(err).To(gomega.MatchError("name and label selector are set"))
+	err = RegisterControllers(mgr, nil)
+	g.Expect(err).To(gomega.MatchError("invalid secret name selector: error parsing regexp: missing closing ]: `[`"))
 }
 
 func TestRegisterControllers(t *testing.T) {
@@ -51,10 +57,76 @@ func TestRegisterControllers(t *testing.T) {
 	mgr, err := ctrl.NewManager(&rest.Config{}, manager.Options{})
 	g.Expect(err).To(gomega.BeNil())
 
-	err = RegisterControllers(mgr)
+	err = RegisterControllers(mgr, nil)
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestRegisterControllersNamespaceSelector(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	viper.Set(env.SecretLabelSelector, "foo=bar")
+	viper.Set(env.SecretNamespaceLabelSelector, "tenant in (")
+
+	mgr, err := ctrl.NewManager(&rest.Config{}, manager.Options{})
+	g.Expect(err).To(gomega.BeNil())
+
+	err = RegisterControllers(mgr, nil)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("invalid namespace label selector")))
+
+	viper.Set(env.SecretNamespaceLabelSelector, "tenant")
+
+	// the controller has already been registered by other tests
+	mgr, err = ctrl.NewManager(&rest.Config{}, manager.Options{Controller: config.Controller{SkipNameValidation: ptr.To(true)}})
+	g.Expect(err).To(gomega.BeNil())
+
+	err = RegisterControllers(mgr, nil)
 	g.Expect(err).To(gomega.BeNil())
 }
 
+func TestRegisterControllersPolling(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	viper.Set(env.SecretPollNames, "a/foo")
+	viper.Set(env.SecretNamespaceLabelSelector, "tenant")
+
+	mgr, err := ctrl.NewManager(&rest.Config{}, manager.Options{})
+	g.Expect(err).To(gomega.BeNil())
+
+	err = RegisterControllers(mgr, nil)
+	g.Expect(err).To(gomega.MatchError("polling secrets cannot be combined with namespace label or name selectors"))
+
+	viper.Set(env.SecretNamespaceLabelSelector, "")
+
+	// no controller is registered, the secrets are polled instead
+	err = RegisterControllers(mgr, nil)
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestSecretsIn(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	viper.Set(env.SecretNameSelector, "foo-.*")
+
+	filter, err := createFilter()
+	g.Expect(err).To(gomega.BeNil())
+
+	c := fake.NewClientBuilder().WithObjects(
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "foo-1"}},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "bar-1"}},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "foo-2"}},
+	).Build()
+
+	// only matching secrets of the namespace are returned
+	requests := secretsIn(c, filter)(context.TODO(), "a")
+	g.Expect(requests).To(gomega.ConsistOf(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "a", Name: "foo-1"}}))
+}
+
 func TestCreateFilterNoConfiguration(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 
@@ -63,19 +135,52 @@ func TestCreateFilterNoConfiguration(t *testing.T) {
 	g.Expect(filter).To(gomega.BeNil())
 }
 
-func TestCreateFilterTooMuchConfiguration(t *testing.T) {
+func TestCreateFilterCombinedSelectors(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 
 	defer viper.Reset()
 
 	viper.Set(env.SecretLabelSelector, "foo=bar")
-	viper.Set(env.SecretNameSelector, ".*")
+	viper.Set(env.SecretNameSelector, "foo-.*")
+
+	filter, err := createFilter()
+	g.Expect(err).To(gomega.BeNil())
+
+	// Name and label match.
+	g.Expect(filter.Create(createEvent("a", "foo-1", map[string]string{"foo": "bar"}))).To(gomega.BeTrue())
+
+	// Name matches but label does not.
+	g.Expect(filter.Create(createEvent("a", "foo-1", map[string]string{"foo": "baz"}))).To(gomega.BeFalse())
+
+	// Label matches but name does not.
+	g.Expect(filter.Create(createEvent("a", "bar-1", map[string]string{"foo": "bar"}))).To(gomega.BeFalse())
+}
+
+func TestCreateFilterNamespaceSelectorOnly(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	viper.Set(env.SecretNamespaceSelector, "a")
 
 	filter, err := createFilter()
-	g.Expect(err).To(gomega.MatchError("name and label selector are set"))
+	g.Expect(err).To(gomega.MatchError("no secret selector set"))
 	g.Expect(filter).To(gomega.BeNil())
 }
 
+func TestCreateFilterTypeSelector(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	defer viper.Reset()
+
+	viper.Set(env.SecretTypeSelector, string(v1.SecretTypeTLS))
+
+	filter, err := createFilter()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(filter.Create(event.CreateEvent{Object: &v1.Secret{Type: v1.SecretTypeTLS}})).To(gomega.BeTrue())
+	g.Expect(filter.Create(event.CreateEvent{Object: &v1.Secret{Type: v1.SecretTypeOpaque}})).To(gomega.BeFalse())
+}
+
 func TestCreateFilterLabelSelector(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 
@@ -97,7 +202,7 @@ func TestCreateFilterInvalidLabelSelector(t *testing.T) {
 	viper.Set(env.SecretLabelSelector, "foo=bar=42")
 
 	filter, err := createFilter()
-	g.Expect(err).To(gomega.MatchError("couldn't parse the selector string \"foo=bar=42\": found '=', expected: ',' or 'end of string'"))
+	g.Expect(err).To(gomega.MatchError("invalid secret label selector: couldn't parse the selector string \"foo=bar=42\": found '=', expected: ',' or 'end of string'"))
 	g.Expect(filter).To(gomega.BeNil())
 }
 
@@ -143,7 +248,7 @@ func TestCreateFilterInvalidNameSelector(t *testing.T) {
 	viper.Set(env.SecretNameSelector, "[")
 
 	filter, err := createFilter()
-	g.Expect(err).To(gomega.MatchError("error parsing regexp: missing closing ]: `[`"))
+	g.Expect(err).To(gomega.MatchError("invalid secret name selector: error parsing regexp: missing closing ]: `[`"))
 	g.Expect(filter).To(gomega.BeNil())
 }
 
@@ -168,86 +273,27 @@ func TestCreateFilterNameAndNamespaceSelector(t *testing.T) {
 	g.Expect(filter.Create(createEvent("b", "bar-1", map[string]string{}))).To(gomega.BeFalse())
 }
 
-func TestMatchByLabelSelectorSimple(t *testing.T) {
+func TestCreateFilterCondition(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 
-	filter, err := matchByLabelSelector("foo=bar")
-	g.Expect(err).To(gomega.BeNil())
-
-	g.Expect(filter.Create(buildLabelsEvent(map[string]string{
-		"foo": "bar",
-	}))).To(gomega.BeTrue())
-
-	g.Expect(filter.Create(buildLabelsEvent(map[string]string{
-		"foo": "baz",
-	}))).To(gomega.BeFalse())
-}
-
-func TestMatchByLabelSelectorIn(t *testing.T) {
-	g := gomega.NewGomegaWithT(t)
-
-	filter, err := matchByLabelSelector("foo in (bar, baz)")
-	g.Expect(err).To(gomega.BeNil())
-
-	g.Expect(filter.Create(buildLabelsEvent(map[string]string{
-		"foo": "bar",
-	}))).To(gomega.BeTrue())
-
-	g.Expect(filter.Create(buildLabelsEvent(map[string]string{
-		"foo": "baz",
-	}))).To(gomega.BeTrue())
-
-	g.Expect(filter.Create(buildLabelsEvent(map[string]string{
-		"foo": "42",
-	}))).To(gomega.BeFalse())
-}
-
-func TestMatchByLabelSelectorInvalid(t *testing.T) {
-	g := gomega.NewGomegaWithT(t)
-
-	filter, err := matchByLabelSelector("foo=bar=42")
-	g.Expect(err).To(gomega.MatchError("couldn't parse the selector string \"foo=bar=42\": found '=', expected: ',' or 'end of string'"))
-	g.Expect(filter).To(gomega.BeNil())
-}
+	defer viper.Reset()
 
-func TestMatchByName(t *testing.T) {
-	g := gomega.NewGomegaWithT(t)
+	viper.Set(env.SecretNameSelector, "foo-.*")
+	viper.Set(env.SecretCondition, `{{ eq .ObjectMeta.Labels.stage "prod" }}`)
 
-	filter, err := matchByName("^foo-bar-.*$")
+	filter, err := createFilter()
 	g.Expect(err).To(gomega.BeNil())
 
-	g.Expect(filter.Create(buildNameEvent("foo-bar-"))).To(gomega.BeTrue())
-	g.Expect(filter.Create(buildNameEvent("foo-bar-1"))).To(gomega.BeTrue())
-	g.Expect(filter.Create(buildNameEvent("foo-bar----_"))).To(gomega.BeTrue())
-	g.Expect(filter.Create(buildNameEvent("foo-bar"))).To(gomega.BeFalse())
-	g.Expect(filter.Create(buildNameEvent("ffoo-bar-"))).To(gomega.BeFalse())
-}
+	prod := createEvent("a", "foo-1", map[string]string{"stage": "prod"}).Object
+	dev := createEvent("a", "foo-1", map[string]string{"stage": "dev"}).Object
 
-func TestCreateNameSelectorInvalid(t *testing.T) {
-	g := gomega.NewGomegaWithT(t)
+	g.Expect(filter.Create(event.CreateEvent{Object: prod})).To(gomega.BeTrue())
+	g.Expect(filter.Create(event.CreateEvent{Object: dev})).To(gomega.BeFalse())
 
-	filter, err := matchByName("[")
-	g.Expect(err).To(gomega.MatchError("error parsing regexp: missing closing ]: `[`"))
-	g.Expect(filter).To(gomega.BeNil())
-}
-
-func TestMatchByNamespace(t *testing.T) {
-	g := gomega.NewGomegaWithT(t)
-
-	filter := matchByNamespace([]string{})
-	g.Expect(filter.Create(buildNamespaceEvent("foo"))).To(gomega.BeTrue())
-	g.Expect(filter.Create(buildNamespaceEvent("bar"))).To(gomega.BeTrue())
-	g.Expect(filter.Create(buildNamespaceEvent("baz"))).To(gomega.BeTrue())
-
-	filter = matchByNamespace([]string{"foo"})
-	g.Expect(filter.Create(buildNamespaceEvent("foo"))).To(gomega.BeTrue())
-	g.Expect(filter.Create(buildNamespaceEvent("bar"))).To(gomega.BeFalse())
-	g.Expect(filter.Create(buildNamespaceEvent("baz"))).To(gomega.BeFalse())
-
-	filter = matchByNamespace([]string{"foo", "bar"})
-	g.Expect(filter.Create(buildNamespaceEvent("foo"))).To(gomega.BeTrue())
-	g.Expect(filter.Create(buildNamespaceEvent("bar"))).To(gomega.BeTrue())
-	g.Expect(filter.Create(buildNamespaceEvent("baz"))).To(gomega.BeFalse())
+	// updates are passed on, if the secret matched before or matches now
+	g.Expect(filter.Update(event.UpdateEvent{ObjectOld: dev, ObjectNew: prod})).To(gomega.BeTrue())
+	g.Expect(filter.Update(event.UpdateEvent{ObjectOld: prod, ObjectNew: dev})).To(gomega.BeTrue())
+	g.Expect(filter.Update(event.UpdateEvent{ObjectOld: dev, ObjectNew: dev})).To(gomega.BeFalse())
 }
 
 func TestMatchRelevantEvents(t *testing.T) {
@@ -262,6 +308,12 @@ func TestMatchRelevantEvents(t *testing.T) {
 	g.Expect(filter.Generic(event.GenericEvent{})).To(gomega.BeFalse())
 	g.Expect(filter.Update(event.UpdateEvent{})).To(gomega.BeTrue())
 
+	viper.Set(env.SecretDeletionWatch, false)
+	viper.Set(env.TemplateFile, "/foo")
+	filter = matchRelevantEvents()
+	g.Expect(filter.Delete(event.DeleteEvent{})).To(gomega.BeTrue())
+
+	viper.Set(env.TemplateFile, "")
 	viper.Set(env.SecretDeletionWatch, true)
 	filter = matchRelevantEvents()
 	g.Expect(filter.Create(event.CreateEvent{})).To(gomega.BeTrue())
@@ -289,7 +341,3 @@ func buildLabelsEvent(labels map[string]string) event.CreateEvent {
 func buildNameEvent(name string) event.CreateEvent {
 	return createEvent("", name, map[string]string{})
 }
-
-func buildNamespaceEvent(namespace string) event.CreateEvent {
-	return createEvent(namespace, "", map[string]string{})
-}
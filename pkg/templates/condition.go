@@ -0,0 +1,32 @@
+package templates
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ConditionContext is the data condition templates (see [RenderCondition]) are rendered with. In addition to all fields
+// of [Context], it contains the allow-listed environment variables of the sidecar.
+//
+// Example:
+//
+//	{{ eq .Labels.stage .Env.STAGE }}
+type ConditionContext struct {
+	*Context
+
+	// Env contains the allow-listed environment variables (see [env.SecretConditionEnv]).
+	Env map[string]string
+}
+
+// CompileCondition parses the given condition template and caches it for subsequent calls to [RenderCondition]. See
+// [Compile].
+func CompileCondition(pattern string) error {
+	return compile(pattern, &ConditionContext{Context: NewContext(syntheticSecret), Env: map[string]string{}})
+}
+
+// RenderCondition renders a given Go template with the given environment and the content of the given Kubernetes
+// secret. See [ConditionContext] for the data available in the template.
+func RenderCondition(pattern string, env map[string]string, secret *corev1.Secret) (string, error) {
+	return render(pattern, secret, func() interface{} {
+		return &ConditionContext{Context: NewContext(secret), Env: env}
+	})
+}
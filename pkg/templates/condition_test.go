@@ -0,0 +1,28 @@
+package templates
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRenderCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "foo",
+			Labels: map[string]string{"stage": "prod"},
+		},
+	}
+
+	res, err := RenderCondition("{{ eq .Labels.stage .Env.STAGE }}", map[string]string{"STAGE": "prod"}, secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res).To(Equal("true"))
+
+	g.Expect(CompileCondition("{{ .Env.STAGE }}{{ .Name }}{{ .ObjectMeta.Name }}")).To(Succeed())
+	g.Expect(CompileCondition("{{ .Evn }}")).To(MatchError(ContainSubstring("can't evaluate field Evn in type *templates.ConditionContext")))
+	g.Expect(Compile(`{{ env "HOME" }}`)).To(MatchError(ContainSubstring(`function "env" not defined`)))
+}
@@ -0,0 +1,145 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"text/template"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/spf13/viper"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// FileContext is the data template files are rendered with.
+//
+// Example:
+//
+//	{{range .Secrets}}{{.Labels.company}}: {{.Data.CLIENT_ID}}
+//	{{end}}
+type FileContext struct {
+	// Secrets contains all matching secrets, ordered by namespace and name.
+	Secrets []*Context
+}
+
+// NewFileContext creates the template context for the given Kubernetes secrets. The secrets are not modified.
+func NewFileContext(secrets []corev1.Secret) *FileContext {
+	contexts := make([]*Context, len(secrets))
+	for i := range secrets {
+		contexts[i] = NewContext(&secrets[i])
+	}
+
+	sort.Slice(contexts, func(i, j int) bool {
+		if contexts[i].Namespace != contexts[j].Namespace {
+			return contexts[i].Namespace < contexts[j].Namespace
+		}
+		return contexts[i].Name < contexts[j].Name
+	})
+
+	return &FileContext{Secrets: contexts}
+}
+
+// secret returns a function looking up a single value of a secret by "namespace/name" and key. Missing secrets or keys
+// result in an error in strict mode (see [env.TemplateStrict]) and an empty string otherwise.
+func (c *FileContext) secret(strict bool) func(string, string) (string, error) {
+	return func(namespacedName, key string) (string, error) {
+		for _, s := range c.Secrets {
+			if s.Namespace+"/"+s.Name != namespacedName {
+				continue
+			}
+
+			value, ok := s.Data[key]
+			if !ok && strict {
+				return "", fmt.Errorf("secret %s has no key %q", namespacedName, key)
+			}
+			return value, nil
+		}
+
+		if strict {
+			return "", fmt.Errorf("secret %s not found", namespacedName)
+		}
+		return "", nil
+	}
+}
+
+// CompileFile parses the template file at the given path and caches it for subsequent calls to [RenderFile]. The
+// template is evaluated without any secrets, to detect references to fields not present in [FileContext].
+func CompileFile(filename string) error {
+	tmpl, err := parseFile(filename)
+	if err != nil {
+		return err
+	}
+
+	err = execute(tmpl, new(bytes.Buffer), NewFileContext(nil))
+	if err != nil && isConfigurationError(err) {
+		return fmt.Errorf("validating template file %s failed: %w", filename, err)
+	}
+
+	return nil
+}
+
+// parseFile parses a Go template file, or returns it from the cache, if the file has been parsed before.
+func parseFile(filename string) (*template.Template, error) {
+	key := cacheKey{file: filename, strict: viper.GetBool(env.TemplateStrict), generation: generation()}
+	if tmpl, ok := compiled.Load(key); ok {
+		return tmpl.(*template.Template), nil
+	}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading template file failed: %w", err)
+	}
+
+	tmpl, gen, err := newTemplate(filename)
+	if err != nil {
+		return nil, err
+	}
+	key.generation = gen
+
+	// 'secret' is bound to the actual secrets on execution.
+	tmpl = tmpl.Funcs(template.FuncMap{"secret": (&FileContext{}).secret(false)})
+	if key.strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+
+	tmpl, err = tmpl.Parse(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("parsing template file %s failed: %w", filename, err)
+	}
+
+	compiled.Store(key, tmpl)
+	return tmpl, nil
+}
+
+// execute a parsed template file with the given context.
+func execute(tmpl *template.Template, buf *bytes.Buffer, ctx *FileContext) error {
+	// Clone the template, as binding functions to the current secrets modifies it.
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Funcs(template.FuncMap{"secret": ctx.secret(viper.GetBool(env.TemplateStrict))}).Execute(buf, ctx)
+}
+
+// RenderFile renders the template file at the given path with the content of all given Kubernetes secrets. See
+// [FileContext] for the data available in the template. Additionally, single values can be looked up using the
+// 'secret' function:
+//
+//	{{secret "namespace/name" "key"}}
+func RenderFile(filename string, secrets []corev1.Secret) ([]byte, error) {
+	tmpl, err := parseFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	err = execute(tmpl, buf, NewFileContext(secrets))
+	if err != nil {
+		return nil, fmt.Errorf("executing template file %s failed: %w", filename, err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,67 @@
+package templates
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var fileTestSecrets = []corev1.Secret{
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "b", Labels: map[string]string{"company": "foobar"}},
+		Data:       map[string][]byte{"URL": []byte("jdbc:postgresql://foobar")},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "a", Labels: map[string]string{"company": "acme"}},
+		Data:       map[string][]byte{"URL": []byte("jdbc:postgresql://acme")},
+	},
+}
+
+func TestRenderFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	filename := writeTemplateFile(t, `{{range .Secrets}}{{.Labels.company}}={{.Data.URL}}
+{{end}}acme={{secret "a/db" "URL"}}
+missing={{secret "c/db" "URL"}}`)
+
+	g.Expect(CompileFile(filename)).To(Succeed())
+
+	// secrets are ordered by namespace and name
+	content, err := RenderFile(filename, fileTestSecrets)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(content)).To(Equal("acme=jdbc:postgresql://acme\nfoobar=jdbc:postgresql://foobar\nacme=jdbc:postgresql://acme\nmissing="))
+
+	// missing secrets fail in strict mode
+	viper.Set(env.TemplateStrict, true)
+	content, err = RenderFile(filename, fileTestSecrets)
+	g.Expect(err).To(MatchError(ContainSubstring("error calling secret: secret c/db not found")))
+	g.Expect(IsExecutionError(err)).To(BeTrue())
+	g.Expect(content).To(BeNil())
+}
+
+func TestCompileFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(CompileFile(filepath.Join(t.TempDir(), "missing"))).To(MatchError(fs.ErrNotExist))
+	g.Expect(CompileFile(writeTemplateFile(t, `{{range .Secrets}}`))).To(MatchError(ContainSubstring("unexpected EOF")))
+	g.Expect(CompileFile(writeTemplateFile(t, `{{.Secret}}`))).To(MatchError(ContainSubstring("can't evaluate field Secret in type *templates.FileContext")))
+
+	// without secrets, fields of single secrets are never evaluated
+	g.Expect(CompileFile(writeTemplateFile(t, `{{range .Secrets}}{{.Nmae}}{{end}}`))).To(Succeed())
+}
+
+func writeTemplateFile(t *testing.T, content string) string {
+	filename := filepath.Join(t.TempDir(), "template")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
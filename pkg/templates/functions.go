@@ -0,0 +1,164 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// funcMap contains all functions available in templates. Argument order follows the conventions of
+// https://masterminds.github.io/sprig/, so the last argument can be passed using a pipeline (e.g.
+// '{{ .Data.foo | default "bar" }}').
+var funcMap = template.FuncMap{
+	"split":  strings.Split,
+	"splitN": strings.SplitN,
+	"join":   join,
+
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"trim":    strings.TrimSpace,
+	"replace": replace,
+	"indent":  indent,
+
+	"regexMatch":      regexMatch,
+	"regexReplaceAll": regexReplaceAll,
+
+	"default":  defaultValue,
+	"required": required,
+
+	"b64enc": b64enc,
+	"b64dec": b64dec,
+	"sha256": sha256sum,
+
+	"toYaml":   toYaml,
+	"toJson":   toJson,
+	"fromJson": fromJson,
+}
+
+// join concatenates the elements of a list using the given separator. Non-string elements are formatted using their
+// default format.
+func join(sep string, list interface{}) (string, error) {
+	switch l := list.(type) {
+	case []string:
+		return strings.Join(l, sep), nil
+	case []interface{}:
+		elements := make([]string, len(l))
+		for i, e := range l {
+			elements[i] = fmt.Sprintf("%v", e)
+		}
+		return strings.Join(elements, sep), nil
+	default:
+		return "", fmt.Errorf("cannot join %T", list)
+	}
+}
+
+// replace all occurrences of old by new in s.
+func replace(old, new, s string) string {
+	return strings.ReplaceAll(s, old, new)
+}
+
+// indent every line of s by the given number of spaces.
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// regexMatch returns true, if s contains any match of the regular expression.
+func regexMatch(regex, s string) (bool, error) {
+	return regexp.MatchString(regex, s)
+}
+
+// regexReplaceAll replaces all matches of the regular expression in s by repl. Inside repl, '$' signs are interpreted
+// as in [regexp.Regexp.ReplaceAllString].
+func regexReplaceAll(regex, s, repl string) (string, error) {
+	r, err := regexp.Compile(regex)
+	if err != nil {
+		return "", err
+	}
+	return r.ReplaceAllString(s, repl), nil
+}
+
+// defaultValue returns the given value, unless it is empty. In that case, the default is returned.
+func defaultValue(def, value interface{}) interface{} {
+	if empty(value) {
+		return def
+	}
+	return value
+}
+
+// required returns the given value, unless it is empty. In that case, rendering fails with the given message.
+func required(msg string, value interface{}) (interface{}, error) {
+	if empty(value) {
+		return nil, errors.New(msg)
+	}
+	return value, nil
+}
+
+// empty returns true for nil and zero values as well as empty strings, slices and maps.
+func empty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+func b64enc(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func b64dec(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// sha256sum returns the hex encoded SHA-256 checksum of s.
+func sha256sum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// toYaml encodes the given value as YAML, without a trailing newline.
+func toYaml(value interface{}) (string, error) {
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+func toJson(value interface{}) (string, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func fromJson(s string) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,72 @@
+package templates
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFunctions(t *testing.T) {
+
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "auth-client-acme",
+			Namespace: "some-namespace",
+			Labels: map[string]string{
+				"company": "ACME",
+			},
+		},
+		Data: map[string][]byte{
+			"CLIENT_ID": []byte("  123-456 "),
+			"ENCODED":   []byte("Zm9vYmFy"),
+			"JSON":      []byte(`{"foo":{"bar":"baz"}}`),
+			"MULTILINE": []byte("foo\nbar"),
+			"EMPTY":     []byte(""),
+		},
+	}
+
+	for _, tt := range []struct {
+		Pattern string
+		Result  string
+		Error   string
+	}{
+		{`{{ join "." (split .ObjectMeta.Name "-") }}`, "auth.client.acme", ""},
+		{`{{ join "," 42 }}`, "", "cannot join int"},
+		{`{{ .ObjectMeta.Labels.company | lower }}`, "acme", ""},
+		{`{{ .ObjectMeta.Name | upper }}`, "AUTH-CLIENT-ACME", ""},
+		{`{{ .Data.CLIENT_ID | trim }}`, "123-456", ""},
+		{`{{ .ObjectMeta.Name | replace "-" "_" }}`, "auth_client_acme", ""},
+		{`{{ .Data.MULTILINE | indent 2 }}`, "  foo\n  bar", ""},
+		{`{{ regexMatch "^auth-" .ObjectMeta.Name }}`, "true", ""},
+		{`{{ regexMatch "^client-" .ObjectMeta.Name }}`, "false", ""},
+		{`{{ regexReplaceAll "^auth-client-(.*)$" .ObjectMeta.Name "${1}" }}`, "acme", ""},
+		{`{{ regexReplaceAll "[" .ObjectMeta.Name "" }}`, "", "error parsing regexp: missing closing ]: `[`"},
+		{`{{ .Data.EMPTY | default "foo" }}`, "foo", ""},
+		{`{{ .ObjectMeta.Labels.missing | default "foo" }}`, "foo", ""},
+		{`{{ .ObjectMeta.Labels.company | default "foo" }}`, "ACME", ""},
+		{`{{ .ObjectMeta.Labels.company | required "company missing" }}`, "ACME", ""},
+		{`{{ .Data.EMPTY | required "empty is empty" }}`, "", "empty is empty"},
+		{`{{ .ObjectMeta.Name | b64enc }}`, "YXV0aC1jbGllbnQtYWNtZQ==", ""},
+		{`{{ .Data.ENCODED | b64dec }}`, "foobar", ""},
+		{`{{ .Data.MULTILINE | b64dec }}`, "", "illegal base64 data at input byte 5"},
+		{`{{ .ObjectMeta.Name | sha256 }}`, "4bbc864a29ff60c96e12f0dd6c53138512752a324a9ef19ea0fd406893ca10b0", ""},
+		{`{{ .ObjectMeta.Labels | toYaml }}`, "company: ACME", ""},
+		{`{{ .ObjectMeta.Labels | toJson }}`, `{"company":"ACME"}`, ""},
+		{`{{ (.Data.JSON | fromJson).foo.bar }}`, "baz", ""},
+		{`{{ .Data.MULTILINE | fromJson }}`, "", "invalid character 'o' in literal false (expecting 'a')"},
+	} {
+		t.Run(tt.Pattern, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			res, err := Render(tt.Pattern, secret.DeepCopy())
+			if tt.Error != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.Error)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(res).To(Equal(tt.Result))
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package templates
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// KeyContext is the data key templates (see [RenderKey]) are rendered with. In addition to all fields of [Context], it
+// contains the key to be transformed.
+//
+// Example:
+//
+//	{{.Labels.prefix}}_{{.Key}}
+type KeyContext struct {
+	*Context
+
+	// Key of the secret data entry.
+	Key string
+}
+
+// CompileKey parses the given key template and caches it for subsequent calls to [RenderKey]. See [Compile].
+func CompileKey(pattern string) error {
+	return compile(pattern, &KeyContext{Context: NewContext(syntheticSecret), Key: "synthetic"})
+}
+
+// RenderKey renders a given Go template with the given key and the content of the given Kubernetes secret. See
+// [KeyContext] for the data available in the template.
+func RenderKey(pattern, key string, secret *corev1.Secret) (string, error) {
+	return render(pattern, secret, func() interface{} {
+		return &KeyContext{Context: NewContext(secret), Key: key}
+	})
+}
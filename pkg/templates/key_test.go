@@ -0,0 +1,27 @@
+package templates
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRenderKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "foo",
+			Labels: map[string]string{"prefix": "app"},
+		},
+	}
+
+	res, err := RenderKey("{{ .Labels.prefix }}.{{ .Key | lower }}", "CLIENT_ID", secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res).To(Equal("app.client_id"))
+
+	g.Expect(CompileKey("{{ .Key }}{{ .Name }}{{ .ObjectMeta.Name }}")).To(Succeed())
+	g.Expect(CompileKey("{{ .Kye }}")).To(MatchError(ContainSubstring("can't evaluate field Kye in type *templates.KeyContext")))
+}
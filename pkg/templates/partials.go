@@ -0,0 +1,135 @@
+package templates
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"text/template"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// partialSet contains all shared named templates. The generation is incremented on every reload, so templates compiled
+// with an outdated set are no longer taken from the cache.
+type partialSet struct {
+	tmpl       *template.Template
+	generation uint64
+}
+
+var partials atomic.Pointer[partialSet]
+
+// LoadPartials parses all files in the given directory as named templates, which can be used in every template via
+// '{{template "name" .}}'. The name of a template is the file name without extension, so 'clientName.tmpl' can be used
+// as '{{template "clientName" .}}'. Additional templates defined within the files via '{{define "name"}}' are available
+// as well. Hidden files and subdirectories are ignored.
+func LoadPartials(dir string) error {
+	set := template.New("partials").Funcs(funcMap)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading template directory failed: %w", err)
+	}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		// Follow symlinks, as used e.g. by mounted ConfigMaps.
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			continue
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if _, err := set.New(name).Parse(string(b)); err != nil {
+			return fmt.Errorf("parsing template %s failed: %w", path, err)
+		}
+	}
+
+	generation := uint64(1)
+	if current := partials.Load(); current != nil {
+		generation = current.generation + 1
+	}
+
+	partials.Store(&partialSet{tmpl: set, generation: generation})
+	compiled.Clear()
+	return nil
+}
+
+// WatchPartials reloads the templates in the given directory on every change, until the context is done. Templates,
+// which cannot be parsed, are logged and the previously loaded ones are kept.
+func WatchPartials(ctx context.Context, dir string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(dir); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+
+			if err := LoadPartials(dir); err != nil {
+				slog.Error("reloading templates failed", "dir", dir, "error", err)
+				continue
+			}
+			slog.Info("reloaded templates", "dir", dir)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error("watching templates failed", "dir", dir, "error", err)
+		}
+	}
+}
+
+// newTemplate allocates a new, undefined template with access to all partials. Returns the generation of the partials
+// used.
+func newTemplate(name string) (*template.Template, uint64, error) {
+	set := partials.Load()
+	if set == nil {
+		return template.New(name).Funcs(funcMap), 0, nil
+	}
+
+	// Clone the partials, so parsing does not add templates to the shared set.
+	tmpl, err := set.tmpl.Clone()
+	if err != nil {
+		return nil, 0, err
+	}
+	return tmpl.New(name), set.generation, nil
+}
+
+// generation returns the generation of the currently loaded partials.
+func generation() uint64 {
+	if set := partials.Load(); set != nil {
+		return set.generation
+	}
+	return 0
+}
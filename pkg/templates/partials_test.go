@@ -0,0 +1,78 @@
+package templates
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLoadPartials(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer partials.Store(nil)
+
+	dir := t.TempDir()
+	writePartial(t, dir, "clientName.tmpl", `{{ with $arr := splitN .Name "-" 3 }}{{ index $arr 2 }}{{ end }}`)
+	writePartial(t, dir, "defines.tmpl", `{{ define "upperName" }}{{ .Name | upper }}{{ end }}`)
+	writePartial(t, dir, ".hidden", `{{ .Missing }`)
+	g.Expect(os.Mkdir(filepath.Join(dir, "subdir"), 0755)).To(Succeed())
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "auth-client-acme"}}
+
+	// partials are unknown before loading
+	_, err := Render(`{{ template "clientName" . }}`, secret)
+	g.Expect(err).To(MatchError(ContainSubstring(`template "clientName" not defined`)))
+	g.Expect(Compile(`{{ template "clientName" . }}`)).To(MatchError(ContainSubstring(`template "clientName" not defined`)))
+
+	g.Expect(LoadPartials(dir)).To(Succeed())
+
+	res, err := Render(`clients.{{ template "clientName" . }}.{{ template "upperName" . }}`, secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res).To(Equal("clients.acme.AUTH-CLIENT-ACME"))
+
+	// reloading replaces cached templates
+	writePartial(t, dir, "clientName.tmpl", `{{ .Name }}`)
+	g.Expect(LoadPartials(dir)).To(Succeed())
+
+	res, err = Render(`clients.{{ template "clientName" . }}`, secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res).To(Equal("clients.auth-client-acme"))
+
+	// invalid partials are rejected
+	writePartial(t, dir, "invalid.tmpl", `{{ .Name }`)
+	g.Expect(LoadPartials(dir)).To(MatchError(ContainSubstring("invalid.tmpl failed")))
+
+	g.Expect(LoadPartials(filepath.Join(dir, "missing"))).To(MatchError(ContainSubstring("reading template directory failed")))
+}
+
+func TestWatchPartials(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer partials.Store(nil)
+
+	dir := t.TempDir()
+	writePartial(t, dir, "clientName.tmpl", `foo`)
+	g.Expect(LoadPartials(dir)).To(Succeed())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go WatchPartials(ctx, dir)
+
+	secret := &corev1.Secret{}
+	g.Eventually(func() string {
+		// the watch might not yet be established, so keep writing
+		writePartial(t, dir, "clientName.tmpl", `bar`)
+		res, _ := Render(`{{ template "clientName" . }}`, secret)
+		return res
+	}, 5*time.Second, 50*time.Millisecond).Should(Equal("bar"))
+}
+
+func writePartial(t *testing.T, dir, name, content string) {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
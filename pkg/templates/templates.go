@@ -2,48 +2,172 @@ package templates
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"text/template"
 
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
+	"github.com/spf13/viper"
+
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// TODO: Do we want to support additional functions?
-var funcMap = template.FuncMap{
-	"split":  strings.Split,
-	"splitN": strings.SplitN,
+// Context is the data every template is rendered with.
+//
+// Example:
+//
+//	{{.Namespace}}/{{.Name}}: {{.Data.CLIENT_ID}} ({{.Labels.company}})
+type Context struct {
+	// Name of the secret.
+	Name string
+	// Namespace of the secret.
+	Namespace string
+	// Labels of the secret.
+	Labels map[string]string
+	// Annotations of the secret.
+	Annotations map[string]string
+	// Data of the secret, converted to strings.
+	Data map[string]string
+	// Binary is the unconverted data of the secret.
+	Binary map[string][]byte
+	// Type of the secret, e.g. 'kubernetes.io/tls'.
+	Type string
+
+	// ObjectMeta of the secret. Deprecated: kept for compatibility with patterns like '{{.ObjectMeta.Name}}'. Use
+	// '{{.Name}}', '{{.Labels}}', etc. instead.
+	ObjectMeta metav1.ObjectMeta
 }
 
-// Render a given Go template with the content of the given Kubernetes secret.
-func Render(pattern string, secret *corev1.Secret) (string, error) {
+// NewContext creates the template context for the given Kubernetes secret. The secret is not modified.
+func NewContext(secret *corev1.Secret) *Context {
+	data := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+
+	return &Context{
+		Name:        secret.Name,
+		Namespace:   secret.Namespace,
+		Labels:      secret.Labels,
+		Annotations: secret.Annotations,
+		Data:        data,
+		Binary:      secret.Data,
+		Type:        string(secret.Type),
+		ObjectMeta:  secret.ObjectMeta,
+	}
+}
+
+// compiled caches parsed templates by their [cacheKey].
+var compiled sync.Map
+
+type cacheKey struct {
+	pattern    string
+	file       string
+	strict     bool
+	generation uint64
+}
+
+// syntheticSecret is used to validate templates, before any actual secret is known.
+var syntheticSecret = &corev1.Secret{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "synthetic",
+		Namespace: "synthetic",
+	},
+}
+
+// Compile parses the given Go template and caches it for subsequent calls to [Render]. The template is evaluated
+// against a synthetic secret, to detect references to fields not present in [Context] or to undefined partials. Errors
+// depending on actual secret content (e.g. missing map entries) are not reported.
+func Compile(pattern string) error {
+	return compile(pattern, NewContext(syntheticSecret))
+}
+
+// compile parses the given Go template and evaluates it with the given data.
+func compile(pattern string, data interface{}) error {
 	if !strings.Contains(pattern, "{{") {
-		// Not a Go template. Return as is.
-		return pattern, nil
+		// Not a Go template. Nothing to compile.
+		return nil
 	}
 
-	// Copy binary '.Data' to '.StringData'.
-	if strings.Contains(pattern, ".Data") {
-		if secret.StringData == nil {
-			secret.StringData = map[string]string{}
-		}
+	tmpl, err := parse(pattern)
+	if err != nil {
+		return err
+	}
 
-		for k, v := range secret.Data {
-			secret.StringData[k] = string(v)
-		}
+	err = tmpl.Execute(new(bytes.Buffer), data)
+	if err != nil && isConfigurationError(err) {
+		return fmt.Errorf("validating template %q failed: %w", pattern, err)
+	}
+
+	return nil
+}
 
-		// Replace occurrences in pattern.
-		pattern = strings.Replace(pattern, ".Data", ".StringData", -1)
+// isConfigurationError returns true for execution errors, which do not depend on the content of a secret.
+func isConfigurationError(err error) bool {
+	return strings.Contains(err.Error(), "can't evaluate field") || strings.Contains(err.Error(), "not defined")
+}
+
+// parse a Go template, or return it from the cache, if the pattern has been parsed before. In strict mode (see
+// [env.TemplateStrict]), referencing missing map keys fails the execution instead of rendering "<no value>". All
+// partials (see [LoadPartials]) are available in the template.
+func parse(pattern string) (*template.Template, error) {
+	key := cacheKey{pattern: pattern, strict: viper.GetBool(env.TemplateStrict), generation: generation()}
+	if tmpl, ok := compiled.Load(key); ok {
+		return tmpl.(*template.Template), nil
 	}
 
 	// See https://pkg.go.dev/text/template
-	tmpl, err := template.New("").Funcs(funcMap).Parse(pattern)
+	tmpl, gen, err := newTemplate("")
+	if err != nil {
+		return nil, err
+	}
+	key.generation = gen
+	if key.strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+
+	tmpl, err = tmpl.Parse(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template %q failed: %w", pattern, err)
+	}
+
+	compiled.Store(key, tmpl)
+	return tmpl, nil
+}
+
+// IsExecutionError returns true, if the error occurred while executing a template with the content of a secret (e.g. a
+// missing key in strict mode). Other than parse errors, these errors depend on the secret and not on the pattern.
+func IsExecutionError(err error) bool {
+	var execErr template.ExecError
+	return errors.As(err, &execErr)
+}
+
+// Render a given Go template with the content of the given Kubernetes secret. See [Context] for the data available in
+// the template.
+func Render(pattern string, secret *corev1.Secret) (string, error) {
+	return render(pattern, secret, func() interface{} {
+		return NewContext(secret)
+	})
+}
+
+// render a given Go template with the data created for the given Kubernetes secret. The data is only created, if the
+// pattern actually is a template.
+func render(pattern string, secret *corev1.Secret, data func() interface{}) (string, error) {
+	if !strings.Contains(pattern, "{{") {
+		// Not a Go template. Return as is.
+		return pattern, nil
+	}
+
+	tmpl, err := parse(pattern)
 	if err != nil {
-		return "", fmt.Errorf("parsing template %q failed: %w", pattern, err)
+		return "", err
 	}
 
 	buf := new(bytes.Buffer)
-	err = tmpl.Execute(buf, secret)
+	err = tmpl.Execute(buf, data())
 	if err != nil {
 		return "", fmt.Errorf("executing template %q with secret %s/%s failed: %w", pattern, secret.Namespace, secret.Name, err)
 	}
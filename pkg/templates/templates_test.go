@@ -3,7 +3,9 @@ package templates
 import (
 	"testing"
 
+	"github.com/jaconi-io/secret-file-provider/pkg/env"
 	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -26,6 +28,7 @@ func TestRender(t *testing.T) {
 			"key1": []byte("value1"),
 			"key2": []byte("value2"),
 		},
+		Type: corev1.SecretTypeOpaque,
 	}
 
 	// Empty string.
@@ -35,12 +38,12 @@ func TestRender(t *testing.T) {
 
 	// Invalid pattern.
 	res, err = Render("{{ .Data.key1 }", &secret)
-	g.Expect(err).To(MatchError("parsing template \"{{ .StringData.key1 }\" failed: template: :1: unexpected \"}\" in operand"))
+	g.Expect(err).To(MatchError("parsing template \"{{ .Data.key1 }\" failed: template: :1: unexpected \"}\" in operand"))
 	g.Expect(res).To(BeEmpty())
 
 	// Missing value in pattern.
 	res, err = Render("{{ .Missing }}", &secret)
-	g.Expect(err).To(MatchError("executing template \"{{ .Missing }}\" with secret some-namespace/my-super-secret-foo-bar failed: template: :1:3: executing \"\" at <.Missing>: can't evaluate field Missing in type *v1.Secret"))
+	g.Expect(err).To(MatchError("executing template \"{{ .Missing }}\" with secret some-namespace/my-super-secret-foo-bar failed: template: :1:3: executing \"\" at <.Missing>: can't evaluate field Missing in type *templates.Context"))
 	g.Expect(res).To(BeEmpty())
 
 	// No pattern.
@@ -73,8 +76,91 @@ func TestRender(t *testing.T) {
 	g.Expect(err).To(BeNil())
 	g.Expect(res).To(Equal("value1-value2"))
 
+	// Context fields.
+	res, err = Render("{{ .Namespace }}/{{ .Name }} {{ .Labels.label1 }} {{ .Annotations.annotation1 }} {{ .Type }}", &secret)
+	g.Expect(err).To(BeNil())
+	g.Expect(res).To(Equal("some-namespace/my-super-secret-foo-bar labelValue1 annotationValue1 Opaque"))
+
+	// Binary secret value in pattern.
+	res, err = Render("{{ index .Binary.key1 0 }}", &secret)
+	g.Expect(err).To(BeNil())
+	g.Expect(res).To(Equal("118"))
+
+	// Pattern mentioning '.Data' outside of field references.
+	res, err = Render("{{ \".Data\" }}-{{ .Data.key1 }}", &secret)
+	g.Expect(err).To(BeNil())
+	g.Expect(res).To(Equal(".Data-value1"))
+
 	// Some elaborate pattern.
 	res, err = Render("my-{{ with $arr := splitN .ObjectMeta.Name \"-\" 3 }}{{ index $arr 2 }}{{ end }}-stuff", &secret)
 	g.Expect(err).To(BeNil())
 	g.Expect(res).To(Equal("my-secret-foo-bar-stuff"))
+
+	// The secret itself is never modified.
+	g.Expect(secret.StringData).To(BeNil())
+}
+
+func TestCompile(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// No pattern.
+	g.Expect(Compile("")).To(Succeed())
+	g.Expect(Compile("samba-dance")).To(Succeed())
+
+	// Invalid pattern.
+	g.Expect(Compile("{{ .Data.key1 }")).To(MatchError("parsing template \"{{ .Data.key1 }\" failed: template: :1: unexpected \"}\" in operand"))
+
+	// Invalid field reference.
+	g.Expect(Compile("{{ .Labels.foo }}-{{ .Lables.foo }}")).To(MatchError("validating template \"{{ .Labels.foo }}-{{ .Lables.foo }}\" failed: template: :1:28: executing \"\" at <.Lables.foo>: can't evaluate field Lables in type *templates.Context"))
+	g.Expect(Compile("{{ .ObjectMeta.Nmae }}")).To(MatchError(ContainSubstring("can't evaluate field Nmae in type v1.ObjectMeta")))
+
+	// Valid field references.
+	g.Expect(Compile("{{ .Name }}{{ .Namespace }}{{ .Labels.foo }}{{ .Annotations.foo }}{{ .Data.foo }}{{ .Binary.foo }}{{ .Type }}")).To(Succeed())
+	g.Expect(Compile("{{ .ObjectMeta.Name }}{{ .ObjectMeta.Labels.foo }}")).To(Succeed())
+
+	// Errors depending on secret content are ignored.
+	g.Expect(Compile("{{ with $arr := splitN .Name \"-\" 3 }}{{ index $arr 2 }}{{ end }}")).To(Succeed())
+	g.Expect(Compile("{{ .Data.foo | required \"foo is missing\" }}")).To(Succeed())
+
+	// Compiled templates are cached.
+	_, ok := compiled.Load(cacheKey{pattern: "{{ .Name }}{{ .Namespace }}{{ .Labels.foo }}{{ .Annotations.foo }}{{ .Data.foo }}{{ .Binary.foo }}{{ .Type }}"})
+	g.Expect(ok).To(BeTrue())
+}
+
+func TestRenderStrict(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer viper.Reset()
+
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Data: map[string][]byte{
+			"CLIENT_ID": []byte("value1"),
+		},
+	}
+
+	// Missing keys are rendered as "<no value>" by default.
+	viper.Set(env.TemplateStrict, env.DefaultTemplateStrict)
+	res, err := Render("{{ .Data.CLIENT_ID }}-{{ .Data.CLIENTID }}", &secret)
+	g.Expect(err).To(BeNil())
+	g.Expect(res).To(Equal("value1-<no value>"))
+
+	// Missing keys fail in strict mode.
+	viper.Set(env.TemplateStrict, true)
+	res, err = Render("{{ .Data.CLIENT_ID }}-{{ .Data.CLIENTID }}", &secret)
+	g.Expect(err).To(MatchError("executing template \"{{ .Data.CLIENT_ID }}-{{ .Data.CLIENTID }}\" with secret bar/foo failed: template: :1:30: executing \"\" at <.Data.CLIENTID>: map has no entry for key \"CLIENTID\""))
+	g.Expect(IsExecutionError(err)).To(BeTrue())
+	g.Expect(res).To(BeEmpty())
+
+	// Optional keys can still be accessed using 'index'.
+	res, err = Render("{{ index .Data \"CLIENTID\" | default \"none\" }}", &secret)
+	g.Expect(err).To(BeNil())
+	g.Expect(res).To(Equal("none"))
+
+	// Parse errors are no execution errors.
+	_, err = Render("{{ .Data.CLIENT_ID }", &secret)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(IsExecutionError(err)).To(BeFalse())
 }